@@ -0,0 +1,262 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var (
+	errMetadataSize   = errors.New("Heartbeat metadata is too large")
+	errMetadataSchema = errors.New("Heartbeat metadata may only have runtime, model, task, capabilities, queue_depth and last_error")
+)
+
+// heartbeatMetadata is the runtime snapshot a heartbeat may carry
+type heartbeatMetadata struct {
+	Runtime      string   `json:"runtime,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Task         string   `json:"task,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	QueueDepth   *int     `json:"queue_depth,omitempty"`
+	LastError    string   `json:"last_error,omitempty"`
+}
+
+// heartbeatConfig holds heartbeat coalescing, history and retention settings
+type heartbeatConfig struct {
+	MinInterval      time.Duration
+	History          bool
+	RawRetention     time.Duration
+	HistoryRetention time.Duration
+}
+
+// heartbeatHour is the start of the UTC hour bucket a heartbeat falls into
+func heartbeatHour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// heartbeatDue reports whether a heartbeat is worth writing: the first one,
+// a status change, or MinInterval after the last write. Metadata changes wait
+// for MinInterval too, so a client cannot force a write on every beat.
+func heartbeatDue(prevStatus string, prevSeen time.Time, status string, now time.Time, minInterval time.Duration) bool {
+	return prevSeen.IsZero() || status != prevStatus || now.Sub(prevSeen) >= minInterval
+}
+
+// parseHeartbeatMetadata validates heartbeat metadata against the schema and
+// returns it in canonical form. Omitted or null metadata returns nil, which
+// leaves the stored snapshot alone; an empty object clears it.
+func parseHeartbeatMetadata(raw json.RawMessage, maxBytes int) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if len(raw) > maxBytes {
+		return nil, errMetadataSize
+	}
+
+	var meta heartbeatMetadata
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&meta); err != nil {
+		return nil, errMetadataSchema
+	}
+
+	for _, field := range []struct {
+		name  string
+		value *string
+		max   int
+	}{
+		{"runtime", &meta.Runtime, 64},
+		{"model", &meta.Model, 128},
+		{"task", &meta.Task, 280},
+		{"last_error", &meta.LastError, 500},
+	} {
+		*field.value = strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(*field.value) > field.max {
+			return nil, fmt.Errorf("Heartbeat metadata %s must be at most %d characters", field.name, field.max)
+		}
+	}
+
+	capabilities := make([]string, 0, len(meta.Capabilities))
+	for _, capability := range meta.Capabilities {
+		capability = strings.TrimSpace(capability)
+		if capability == "" || utf8.RuneCountInString(capability) > 64 {
+			return nil, errors.New("Heartbeat metadata capabilities must be 1 to 64 characters each")
+		}
+		if !slices.Contains(capabilities, capability) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	if len(capabilities) > 32 {
+		return nil, errors.New("Heartbeat metadata may list at most 32 capabilities")
+	}
+	meta.Capabilities = capabilities
+
+	if meta.QueueDepth != nil && (*meta.QueueDepth < 0 || *meta.QueueDepth > 1000000) {
+		return nil, errors.New("Heartbeat metadata queue_depth must be between 0 and 1000000")
+	}
+
+	return json.Marshal(meta)
+}
+
+// minuteMask is an hour's online_minutes with only the given minute set
+func minuteMask(minute int) string {
+	return strings.Repeat("0", minute) + "1" + strings.Repeat("0", 59-minute)
+}
+
+// recordHeartbeat upserts an agent's presence row and counts the beat in
+// its hourly rollup. Metadata, when not nil, replaces the stored snapshot.
+// It returns the presence row and whether it was written.
+func recordHeartbeat(app core.App, agentId string, status string, metadata []byte, now time.Time, cfg heartbeatConfig) (*core.Record, bool, error) {
+	presence, err := app.FindFirstRecordByData("presence", "agent", agentId)
+	if err != nil {
+		collection, err := app.FindCollectionByNameOrId("presence")
+		if err != nil {
+			return nil, false, err
+		}
+		presence = core.NewRecord(collection)
+		presence.Set("agent", agentId)
+	}
+
+	if !heartbeatDue(presence.GetString("status"), presence.GetDateTime("last_seen").Time(), status, now, cfg.MinInterval) {
+		return presence, false, nil
+	}
+
+	presence.Set("status", status)
+	presence.Set("last_seen", now)
+	if metadata != nil {
+		presence.Set("metadata", types.JSONRaw(metadata))
+	}
+	if err := app.Save(presence); err != nil {
+		return nil, false, err
+	}
+
+	if cfg.History {
+		seen, _ := types.ParseDateTime(now)
+		hour, _ := types.ParseDateTime(heartbeatHour(now))
+		online := 0
+		mask := strings.Repeat("0", 60)
+		if status == "online" {
+			online = 1
+			mask = minuteMask(now.UTC().Minute())
+		}
+		_, err := app.DB().NewQuery(`
+			INSERT INTO heartbeat_hours (id, agent, hour, beats, online_beats, online_minutes, first_seen, last_seen, created, updated)
+			VALUES ({:id}, {:agent}, {:hour}, 1, {:online}, {:mask}, {:seen}, {:seen}, {:seen}, {:seen})
+			ON CONFLICT (agent, hour) DO UPDATE SET
+				beats = beats + 1,
+				online_beats = online_beats + excluded.online_beats,
+				online_minutes = CASE WHEN excluded.online_beats = 1
+					THEN substr(online_minutes, 1, {:minute}) || '1' || substr(online_minutes, {:minute} + 2)
+					ELSE online_minutes END,
+				last_seen = excluded.last_seen,
+				updated = excluded.updated
+		`).Bind(dbx.Params{
+			"id":     core.GenerateDefaultRandomId(),
+			"agent":  agentId,
+			"hour":   hour.String(),
+			"online": online,
+			"mask":   mask,
+			"minute": now.UTC().Minute(),
+			"seen":   seen.String(),
+		}).Execute()
+		if err != nil {
+			return presence, true, err
+		}
+	}
+
+	return presence, true, nil
+}
+
+// pruneHeartbeats deletes raw heartbeats and rollups past their retention
+func pruneHeartbeats(app core.App, cfg heartbeatConfig, now time.Time) error {
+	rawBefore, _ := types.ParseDateTime(now.Add(-cfg.RawRetention))
+	if _, err := app.DB().Delete("heartbeats", dbx.NewExp("updated < {:before}", dbx.Params{"before": rawBefore.String()})).Execute(); err != nil {
+		return err
+	}
+
+	historyBefore, _ := types.ParseDateTime(now.Add(-cfg.HistoryRetention))
+	_, err := app.DB().Delete("heartbeat_hours", dbx.NewExp("hour < {:before}", dbx.Params{"before": historyBefore.String()})).Execute()
+	return err
+}
+
+// RegisterHeartbeats keeps one presence row per agent up to date from
+// heartbeats, rolls them up per hour and prunes old data
+func RegisterHeartbeats(app *pocketbase.PocketBase) {
+	cfg := heartbeatConfig{
+		MinInterval:      time.Duration(envInt("HEARTBEAT_MIN_INTERVAL_SECONDS", 15)) * time.Second,
+		History:          envInt("HEARTBEAT_HISTORY", 1) == 1,
+		RawRetention:     time.Duration(envInt("HEARTBEAT_RAW_RETENTION_HOURS", 24)) * time.Hour,
+		HistoryRetention: time.Duration(envInt("HEARTBEAT_HISTORY_DAYS", 90)) * 24 * time.Hour,
+	}
+	metadataMax := envInt("HEARTBEAT_METADATA_MAX_BYTES", 2048)
+
+	// Heartbeats written through the collection API still update presence;
+	// their raw rows are pruned after RawRetention
+	legacy := func(e *core.RecordEvent) error {
+		if _, _, err := recordHeartbeat(e.App, e.Record.GetString("agent"), e.Record.GetString("status"), nil, time.Now(), cfg); err != nil {
+			e.App.Logger().Warn("Failed to record heartbeat", "agent", e.Record.GetString("agent"), "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("heartbeats").BindFunc(legacy)
+	app.OnRecordAfterUpdateSuccess("heartbeats").BindFunc(legacy)
+
+	app.Cron().MustAdd("prune_heartbeats", "15 * * * *", func() {
+		if err := pruneHeartbeats(app, cfg, time.Now()); err != nil {
+			app.Logger().Warn("Failed to prune heartbeats", "error", err)
+		}
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Heartbeat: upsert the caller's presence
+		e.Router.POST("/api/agents/heartbeat", func(re *core.RequestEvent) error {
+			if re.Auth == nil || re.Auth.Collection().Name != "agents" {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			var body struct {
+				Status   string          `json:"status"`
+				Metadata json.RawMessage `json:"metadata"`
+			}
+			_ = re.BindBody(&body)
+			if body.Status == "" {
+				body.Status = "online"
+			}
+			if body.Status != "online" && body.Status != "away" {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "status must be online or away"})
+			}
+
+			metadata, err := parseHeartbeatMetadata(body.Metadata, metadataMax)
+			if err != nil {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			presence, _, err := recordHeartbeat(app, re.Auth.Id, body.Status, metadata, time.Now(), cfg)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record heartbeat"})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success":  true,
+				"status":   presence.GetString("status"),
+				"lastSeen": presence.GetString("last_seen"),
+				"metadata": presence.Get("metadata"),
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
@@ -75,53 +76,6 @@ func RegisterHooks(app *pocketbase.PocketBase) {
 			return re.String(http.StatusOK, string(content))
 		})
 
-		// Agents presence endpoint
-		e.Router.GET("/api/agents/presence", func(re *core.RequestEvent) error {
-			// Query all heartbeats and calculate presence
-			records, err := app.FindRecordsByFilter(
-				"heartbeats",
-				"created > @now - 300", // Last 5 minutes = online
-				"-created",
-				100,
-				0,
-			)
-			if err != nil {
-				return re.JSON(http.StatusOK, map[string]any{
-					"items":        []any{},
-					"totalOnline":  0,
-					"totalAway":    0,
-					"totalOffline": 0,
-				})
-			}
-
-			// Build presence items
-			items := make([]map[string]any, 0)
-			onlineCount := 0
-			awayCount := 0
-
-			for _, record := range records {
-				status := record.GetString("status")
-				if status == "online" {
-					onlineCount++
-				} else {
-					awayCount++
-				}
-
-				items = append(items, map[string]any{
-					"id":       record.GetString("agent"),
-					"status":   status,
-					"lastSeen": record.GetString("updated"),
-				})
-			}
-
-			return re.JSON(http.StatusOK, map[string]any{
-				"items":        items,
-				"totalOnline":  onlineCount,
-				"totalAway":    awayCount,
-				"totalOffline": 0, // Would need total agents count
-			})
-		})
-
 		// Agent me endpoint
 		e.Router.GET("/api/agents/me", func(re *core.RequestEvent) error {
 			authRecord := re.Auth
@@ -145,3 +99,20 @@ func RegisterHooks(app *pocketbase.PocketBase) {
 		return e.Next()
 	})
 }
+
+// === HELPERS ===
+
+// queryInt parses an integer query parameter, clamped to [lo, hi]
+func queryInt(re *core.RequestEvent, key string, def, lo, hi int) int {
+	n, err := strconv.Atoi(re.Request.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// maxPromotions matches the oracle-net limit per promotion request
+const maxPromotions = 20
+
+// promotionRequest is sent to oracle-net /api/bridge/promote
+type promotionRequest struct {
+	AgentWallet string          `json:"agent_wallet"`
+	Posts       []promotionPost `json:"posts"`
+}
+
+type promotionPost struct {
+	ID      string `json:"id"`
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content"`
+	Created string `json:"created"`
+}
+
+// promotionResponse is oracle-net's answer; each result's status is
+// created, existing, invalid or failed
+type promotionResponse struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Oracle  map[string]any `json:"oracle,omitempty"`
+	Results []struct {
+		SourceID string `json:"source_id"`
+		PostID   string `json:"post_id"`
+		URL      string `json:"url"`
+		Status   string `json:"status"`
+	} `json:"results"`
+}
+
+// bridgeSignature is the hex HMAC-SHA256 of "<timestamp>\n<body>"
+func bridgeSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requestPromotion sends a signed promotion request to oracle-net
+func requestPromotion(client *http.Client, oracleNetURL string, secret string, req promotionRequest) (int, *promotionResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimRight(oracleNetURL, "/")+"/api/bridge/promote", bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Bridge-Timestamp", timestamp)
+	httpReq.Header.Set("X-Bridge-Signature", bridgeSignature(secret, timestamp, body))
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to call oracle-net: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result promotionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to parse oracle-net response: %w", err)
+	}
+	return resp.StatusCode, &result, nil
+}
+
+// RegisterPromotion lets agents cross-post their sandbox posts to oracle-net
+func RegisterPromotion(app *pocketbase.PocketBase) {
+	oracleNetURL := envString("ORACLE_NET_URL", "http://localhost:8090")
+	secret := envString("BRIDGE_SECRET", "")
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	// Promotion fields are only written by the promote endpoint
+	app.OnRecordCreateRequest("sandbox_posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("promoted_post", "")
+		e.Record.Set("promoted_at", "")
+		return e.Next()
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Promote selected sandbox posts to oracle-net
+		e.Router.POST("/api/sandbox_posts/promote", func(re *core.RequestEvent) error {
+			if re.Auth == nil || re.Auth.Collection().Name != "agents" {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+			if secret == "" {
+				return re.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Promotion is not configured"})
+			}
+
+			var body struct {
+				Posts []struct {
+					ID    string `json:"id"`
+					Title string `json:"title"`
+				} `json:"posts"`
+			}
+			if err := re.BindBody(&body); err != nil || len(body.Posts) == 0 {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "posts are required"})
+			}
+			if len(body.Posts) > maxPromotions {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Too many posts in one promotion"})
+			}
+
+			req := promotionRequest{AgentWallet: strings.ToLower(re.Auth.GetString("wallet_address"))}
+			records := map[string]*core.Record{}
+			for _, p := range body.Posts {
+				record, err := app.FindRecordById("sandbox_posts", p.ID)
+				if err != nil || record.GetString("author") != re.Auth.Id {
+					return re.JSON(http.StatusNotFound, map[string]string{"error": "Sandbox post not found: " + p.ID})
+				}
+				records[record.Id] = record
+				req.Posts = append(req.Posts, promotionPost{
+					ID:      record.Id,
+					Title:   p.Title,
+					Content: record.GetString("content"),
+					Created: record.GetString("created"),
+				})
+			}
+
+			status, result, err := requestPromotion(client, oracleNetURL, secret, req)
+			if err != nil {
+				return re.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+			}
+			if status != http.StatusOK {
+				return re.JSON(status, map[string]string{"error": result.Error})
+			}
+
+			// oracle-net only promotes for bridge-verified agents
+			if result.Oracle != nil && !re.Auth.GetBool("verified") {
+				re.Auth.Set("verified", true)
+				if err := app.Save(re.Auth); err != nil {
+					app.Logger().Warn("Failed to mark agent verified", "agent", re.Auth.Id, "error", err)
+				}
+			}
+
+			// Remember where each post went (idempotent: oracle-net returns
+			// the same post id on re-promotion)
+			for _, r := range result.Results {
+				record, ok := records[r.SourceID]
+				if !ok || r.PostID == "" || record.GetString("promoted_post") == r.PostID {
+					continue
+				}
+				record.Set("promoted_post", r.PostID)
+				record.Set("promoted_at", types.NowDateTime())
+				if err := app.Save(record); err != nil {
+					app.Logger().Warn("Failed to record promotion", "sandbox_post", record.Id, "error", err)
+				}
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"oracle":  result.Oracle,
+				"results": result.Results,
+			})
+		})
+
+		return e.Next()
+	})
+}
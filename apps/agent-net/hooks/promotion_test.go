@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+func TestRegisterPromotion(t *testing.T) {
+	// Test that RegisterPromotion doesn't panic
+	app := pocketbase.New()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("RegisterPromotion panicked: %v", r)
+		}
+	}()
+
+	RegisterPromotion(app)
+}
+
+func TestRequestPromotionSignsRequest(t *testing.T) {
+	var received promotionRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/bridge/promote" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		expected := bridgeSignature("s3cret", r.Header.Get("X-Bridge-Timestamp"), body)
+		if r.Header.Get("X-Bridge-Signature") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid bridge signature"})
+			return
+		}
+
+		json.Unmarshal(body, &received)
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"results": []map[string]string{
+				{"source_id": "sb1", "post_id": "p1", "status": "created"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	req := promotionRequest{
+		AgentWallet: "0xabc",
+		Posts:       []promotionPost{{ID: "sb1", Content: "hello", Created: "2026-01-01 00:00:00.000Z"}},
+	}
+
+	status, result, err := requestPromotion(server.Client(), server.URL+"/", "s3cret", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || !result.Success {
+		t.Fatalf("expected success, got %d %+v", status, result)
+	}
+	if len(result.Results) != 1 || result.Results[0].PostID != "p1" {
+		t.Errorf("unexpected results: %+v", result.Results)
+	}
+	if received.AgentWallet != "0xabc" || received.Posts[0].Created == "" {
+		t.Errorf("provenance not forwarded: %+v", received)
+	}
+
+	status, result, err = requestPromotion(server.Client(), server.URL, "wrong", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusUnauthorized || result.Error == "" {
+		t.Errorf("expected rejection, got %d %+v", status, result)
+	}
+}
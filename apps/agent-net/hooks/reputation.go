@@ -0,0 +1,301 @@
+package hooks
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Maximum points per reputation component; they add up to 100
+const (
+	reputationHeartbeatPoints = 30
+	reputationActivityPoints  = 20
+	reputationQualityPoints   = 20
+	reputationAgePoints       = 15
+	reputationVerifiedPoints  = 15
+)
+
+// reputationConfig holds the windows and targets the score is measured against
+type reputationConfig struct {
+	HeartbeatWindow time.Duration
+	PostWindow      time.Duration
+	PostTarget      int
+	PromotedTarget  int
+	MaturityDays    int
+	MinPostLength   int
+}
+
+// reputationSignals are the raw measurements behind a score
+type reputationSignals struct {
+	ActiveHours      int  `json:"active_hours"`
+	WindowHours      int  `json:"window_hours"`
+	Posts            int  `json:"posts"`
+	SubstantialPosts int  `json:"substantial_posts"`
+	UniquePosts      int  `json:"unique_posts"`
+	PromotedPosts    int  `json:"promoted_posts"`
+	AgeDays          int  `json:"age_days"`
+	Verified         bool `json:"verified"`
+}
+
+// reputationBreakdown is a score split by component
+type reputationBreakdown struct {
+	Heartbeats int               `json:"heartbeats"`
+	Activity   int               `json:"activity"`
+	Quality    int               `json:"quality"`
+	Age        int               `json:"age"`
+	Verified   int               `json:"verified"`
+	Total      int               `json:"total"`
+	Signals    reputationSignals `json:"signals"`
+}
+
+// ratio returns n/target clamped to [0, 1]
+func ratio(n int, target int) float64 {
+	if target <= 0 || n <= 0 {
+		return 0
+	}
+	return math.Min(float64(n)/float64(target), 1)
+}
+
+// computeReputation scores an agent from its signals:
+//   - heartbeats: share of hours in the window with at least one heartbeat
+//   - activity: sandbox posts in the window, up to PostTarget
+//   - quality: share of substantial, non-repeated posts, plus promotions
+//   - age: days since sign-up, up to MaturityDays
+//   - verified: bridge-verified agents get the full component
+func computeReputation(s reputationSignals, cfg reputationConfig) reputationBreakdown {
+	b := reputationBreakdown{Signals: s}
+
+	b.Heartbeats = int(math.Round(reputationHeartbeatPoints * ratio(s.ActiveHours, s.WindowHours)))
+	b.Activity = int(math.Round(reputationActivityPoints * ratio(s.Posts, cfg.PostTarget)))
+
+	if s.Posts > 0 {
+		substance := ratio(s.SubstantialPosts, s.Posts) * ratio(s.UniquePosts, s.Posts)
+		promoted := ratio(s.PromotedPosts, cfg.PromotedTarget)
+		b.Quality = int(math.Round(reputationQualityPoints * (substance + promoted) / 2))
+	}
+
+	b.Age = int(math.Round(reputationAgePoints * ratio(s.AgeDays, cfg.MaturityDays)))
+	if s.Verified {
+		b.Verified = reputationVerifiedPoints
+	}
+
+	b.Total = b.Heartbeats + b.Activity + b.Quality + b.Age + b.Verified
+	return b
+}
+
+// loadReputationSignals measures every agent (or only agentId when set)
+func loadReputationSignals(app core.App, cfg reputationConfig, agentId string, now time.Time) (map[string]reputationSignals, error) {
+	agentFilter := dbx.NewExp("1=1")
+	if agentId != "" {
+		agentFilter = dbx.HashExp{"id": agentId}
+	}
+	agents, err := app.FindAllRecords("agents", agentFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	windowHours := int(cfg.HeartbeatWindow / time.Hour)
+	signals := make(map[string]reputationSignals, len(agents))
+	for _, agent := range agents {
+		age := 0
+		if created := agent.GetDateTime("created").Time(); !created.IsZero() {
+			age = int(now.Sub(created) / (24 * time.Hour))
+		}
+		signals[agent.Id] = reputationSignals{
+			WindowHours: windowHours,
+			AgeDays:     age,
+			Verified:    agent.GetBool("verified"),
+		}
+	}
+
+	heartbeatSince, _ := types.ParseDateTime(now.Add(-cfg.HeartbeatWindow))
+	postSince, _ := types.ParseDateTime(now.Add(-cfg.PostWindow))
+
+	// One heartbeat_hours row per agent and hour with at least one heartbeat
+	var heartbeats []struct {
+		Agent string `db:"agent"`
+		Hours int    `db:"hours"`
+	}
+	err = app.DB().NewQuery(`
+		SELECT agent, COUNT(*) AS hours
+		FROM heartbeat_hours
+		WHERE hour >= {:since} AND ({:agent} = '' OR agent = {:agent})
+		GROUP BY agent
+	`).Bind(dbx.Params{"since": heartbeatSince.String(), "agent": agentId}).All(&heartbeats)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range heartbeats {
+		if s, ok := signals[row.Agent]; ok {
+			s.ActiveHours = row.Hours
+			signals[row.Agent] = s
+		}
+	}
+
+	var posts []struct {
+		Author      string `db:"author"`
+		Posts       int    `db:"posts"`
+		Substantial int    `db:"substantial"`
+		Unique      int    `db:"uniq"`
+		Promoted    int    `db:"promoted"`
+	}
+	err = app.DB().NewQuery(`
+		SELECT
+			author,
+			COUNT(*) AS posts,
+			SUM(CASE WHEN length(trim(content)) >= {:min} THEN 1 ELSE 0 END) AS substantial,
+			COUNT(DISTINCT lower(trim(content))) AS uniq,
+			SUM(CASE WHEN promoted_post != '' THEN 1 ELSE 0 END) AS promoted
+		FROM sandbox_posts
+		WHERE created >= {:since} AND ({:agent} = '' OR author = {:agent})
+		GROUP BY author
+	`).Bind(dbx.Params{"since": postSince.String(), "min": cfg.MinPostLength, "agent": agentId}).All(&posts)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range posts {
+		if s, ok := signals[row.Author]; ok {
+			s.Posts, s.SubstantialPosts, s.UniquePosts, s.PromotedPosts = row.Posts, row.Substantial, row.Unique, row.Promoted
+			signals[row.Author] = s
+		}
+	}
+
+	return signals, nil
+}
+
+// refreshReputation recomputes and stores reputation for every agent (or
+// only agentId when set). It writes with SQL so agents.updated keeps
+// meaning "profile changed".
+func refreshReputation(app core.App, cfg reputationConfig, agentId string) error {
+	now := time.Now()
+	signals, err := loadReputationSignals(app, cfg, agentId, now)
+	if err != nil {
+		return err
+	}
+
+	computedAt, _ := types.ParseDateTime(now)
+	for id, s := range signals {
+		score := computeReputation(s, cfg)
+		breakdown, _ := json.Marshal(score)
+		_, err := app.DB().NewQuery(
+			"UPDATE agents SET reputation = {:reputation}, reputation_breakdown = {:breakdown}, reputation_updated = {:at} WHERE id = {:id}",
+		).Bind(dbx.Params{
+			"reputation": score.Total,
+			"breakdown":  string(breakdown),
+			"at":         computedAt.String(),
+			"id":         id,
+		}).Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reputationItem is an agent as listed on the leaderboard
+func reputationItem(agent *core.Record) map[string]any {
+	return map[string]any{
+		"id":             agent.Id,
+		"display_name":   agent.GetString("display_name"),
+		"wallet_address": agent.GetString("wallet_address"),
+		"reputation":     agent.GetInt("reputation"),
+		"verified":       agent.GetBool("verified"),
+	}
+}
+
+// RegisterReputation scores agents from their sandbox activity on a schedule
+// and serves the breakdown and a leaderboard
+func RegisterReputation(app *pocketbase.PocketBase) {
+	cfg := reputationConfig{
+		HeartbeatWindow: time.Duration(envInt("REPUTATION_HEARTBEAT_DAYS", 7)) * 24 * time.Hour,
+		PostWindow:      time.Duration(envInt("REPUTATION_POST_DAYS", 30)) * 24 * time.Hour,
+		PostTarget:      envInt("REPUTATION_POST_TARGET", 30),
+		PromotedTarget:  envInt("REPUTATION_PROMOTED_TARGET", 5),
+		MaturityDays:    envInt("REPUTATION_MATURITY_DAYS", 90),
+		MinPostLength:   envInt("REPUTATION_MIN_POST_LENGTH", 40),
+	}
+
+	// Reputation fields are only written by the engine
+	app.OnRecordCreateRequest("agents").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("reputation_breakdown", nil)
+		e.Record.Set("reputation_updated", "")
+		return e.Next()
+	})
+
+	app.Cron().MustAdd("reputation", envString("REPUTATION_CRON", "*/15 * * * *"), func() {
+		if err := refreshReputation(app, cfg, ""); err != nil {
+			app.Logger().Warn("Failed to refresh reputation", "error", err)
+		}
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Reputation with its components
+		e.Router.GET("/api/agents/{id}/reputation", func(re *core.RequestEvent) error {
+			agent, err := app.FindRecordById("agents", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Agent not found"})
+			}
+
+			// Agents that joined since the last run are scored on first view
+			if agent.GetString("reputation_updated") == "" {
+				if err := refreshReputation(app, cfg, agent.Id); err != nil {
+					return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to compute reputation"})
+				}
+				if agent, err = app.FindRecordById("agents", agent.Id); err != nil {
+					return re.JSON(http.StatusNotFound, map[string]string{"error": "Agent not found"})
+				}
+			}
+
+			var breakdown reputationBreakdown
+			_ = agent.UnmarshalJSONField("reputation_breakdown", &breakdown)
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success":    true,
+				"agent":      reputationItem(agent),
+				"reputation": agent.GetInt("reputation"),
+				"breakdown":  breakdown,
+				"maximum": map[string]int{
+					"heartbeats": reputationHeartbeatPoints,
+					"activity":   reputationActivityPoints,
+					"quality":    reputationQualityPoints,
+					"age":        reputationAgePoints,
+					"verified":   reputationVerifiedPoints,
+				},
+				"computed_at": agent.GetString("reputation_updated"),
+			})
+		})
+
+		// Top agents by reputation
+		e.Router.GET("/api/agents/leaderboard", func(re *core.RequestEvent) error {
+			limit := queryInt(re, "limit", 25, 1, 100)
+
+			agents, err := app.FindRecordsByFilter("agents", "reputation > 0", "-reputation,created", limit, 0)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load leaderboard"})
+			}
+
+			items := make([]map[string]any, 0, len(agents))
+			for i, agent := range agents {
+				item := reputationItem(agent)
+				item["rank"] = i + 1
+				items = append(items, item)
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"items":   items,
+				"count":   len(items),
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,58 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+func TestRegisterReputation(t *testing.T) {
+	// Test that RegisterReputation doesn't panic
+	app := pocketbase.New()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("RegisterReputation panicked: %v", r)
+		}
+	}()
+
+	RegisterReputation(app)
+}
+
+func TestComputeReputation(t *testing.T) {
+	cfg := reputationConfig{PostTarget: 10, PromotedTarget: 2, MaturityDays: 30}
+
+	fresh := computeReputation(reputationSignals{WindowHours: 168}, cfg)
+	if fresh.Total != 0 {
+		t.Errorf("expected 0 for a new idle agent, got %+v", fresh)
+	}
+
+	full := computeReputation(reputationSignals{
+		ActiveHours:      168,
+		WindowHours:      168,
+		Posts:            12,
+		SubstantialPosts: 12,
+		UniquePosts:      12,
+		PromotedPosts:    3,
+		AgeDays:          45,
+		Verified:         true,
+	}, cfg)
+	if full.Total != 100 {
+		t.Errorf("expected the maximum of 100, got %+v", full)
+	}
+
+	partial := computeReputation(reputationSignals{
+		ActiveHours:      84,
+		WindowHours:      168,
+		Posts:            5,
+		SubstantialPosts: 5,
+		UniquePosts:      1,
+		AgeDays:          15,
+	}, cfg)
+	expected := reputationBreakdown{Heartbeats: 15, Activity: 10, Quality: 2, Age: 8}
+	expected.Total = 35
+	if partial.Heartbeats != expected.Heartbeats || partial.Activity != expected.Activity ||
+		partial.Quality != expected.Quality || partial.Age != expected.Age || partial.Total != expected.Total {
+		t.Errorf("expected %+v, got %+v", expected, partial)
+	}
+}
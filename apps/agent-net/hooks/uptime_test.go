@@ -0,0 +1,117 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeline builds minutes from a string of "1" (online) and "0" (offline)
+func timeline(s string) []bool {
+	online := make([]bool, len(s))
+	for i, c := range s {
+		online[i] = c == '1'
+	}
+	return online
+}
+
+func TestSummarizeUptime(t *testing.T) {
+	tests := []struct {
+		minutes  string
+		expected uptimeStat
+	}{
+		{"0011100110", uptimeStat{Minutes: 10, Online: 5, Sessions: 2, LongestOutage: 2, LeadingOutage: 2, TrailingOutage: 1}},
+		{"1111", uptimeStat{Minutes: 4, Online: 4, Sessions: 1}},
+		{"000", uptimeStat{Minutes: 3, LongestOutage: 3, LeadingOutage: 3, TrailingOutage: 3}},
+		{"", uptimeStat{}},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeUptime(time.Time{}, timeline(tt.minutes)); got != tt.expected {
+			t.Errorf("summarizeUptime(%q) = %+v, expected %+v", tt.minutes, got, tt.expected)
+		}
+	}
+}
+
+func TestCombineUptime(t *testing.T) {
+	// Split a timeline into periods and check the combination matches
+	// summarizing it in one go
+	for _, minutes := range []string{
+		"110011|100001|111111|000000|000011",
+		"000000|000000|011000",
+		"111111|111111",
+		"000000",
+	} {
+		var stats []uptimeStat
+		for _, part := range strings.Split(minutes, "|") {
+			stats = append(stats, summarizeUptime(time.Time{}, timeline(part)))
+		}
+		expected := summarizeUptime(time.Time{}, timeline(strings.ReplaceAll(minutes, "|", "")))
+		if got := combineUptime(stats); got != expected {
+			t.Errorf("combineUptime(%q) = %+v, expected %+v", minutes, got, expected)
+		}
+	}
+}
+
+func TestOnlineTimeline(t *testing.T) {
+	from := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	hours := []uptimeHour{
+		// A beat at 09:59 still counts for the first minutes of 10:00
+		{Hour: "2026-01-01 09:00:00.000Z", Minutes: strings.Repeat("0", 59) + "1"},
+		{Hour: "2026-01-01 10:00:00.000Z", Minutes: "00000001" + strings.Repeat("0", 52)},
+	}
+
+	got := onlineTimeline(hours, from, from.Add(12*time.Minute), 3*time.Minute)
+	expected := timeline("110000011100")
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("onlineTimeline() = %v, expected %v", got, expected)
+		}
+	}
+}
+
+func TestUptimePercent(t *testing.T) {
+	if got := (uptimeStat{Minutes: 3, Online: 2}).percent(); got != 66.7 {
+		t.Errorf("expected 66.7, got %v", got)
+	}
+	if got := (uptimeStat{}).percent(); got != 0 {
+		t.Errorf("expected 0 for an empty period, got %v", got)
+	}
+}
+
+func TestParseUptimeRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 7 * 24 * time.Hour, true},
+		{"24h", 24 * time.Hour, true},
+		{"30d", 30 * 24 * time.Hour, true},
+		{"91d", 0, false},
+		{"0d", 0, false},
+		{"7w", 0, false},
+		{"d", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseUptimeRange(tt.value)
+		if ok != tt.ok || ok && got != tt.expected {
+			t.Errorf("parseUptimeRange(%q) = %v, %v, expected %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestUptimeBuckets(t *testing.T) {
+	now := time.Date(2026, 1, 8, 13, 30, 0, 0, time.UTC)
+	starts := uptimeBuckets(7*24*time.Hour, 24*time.Hour, now)
+	if len(starts) != 7 {
+		t.Fatalf("expected 7 days, got %d", len(starts))
+	}
+	if !starts[0].Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) || !starts[6].Equal(time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected days %v .. %v", starts[0], starts[6])
+	}
+	if hours := uptimeBuckets(36*time.Hour, 24*time.Hour, now); len(hours) != 2 {
+		t.Errorf("expected a partial range to round up to 2 days, got %d", len(hours))
+	}
+}
@@ -22,6 +22,11 @@ func main() {
 	// Register custom hooks and routes
 	hooks.RegisterHooks(app)
 	hooks.RegisterSIWE(app)
+	hooks.RegisterPromotion(app)
+	hooks.RegisterReputation(app)
+	hooks.RegisterPresence(app)
+	hooks.RegisterHeartbeats(app)
+	hooks.RegisterUptime(app)
 
 	// Start the server
 	if err := app.Start(); err != nil {
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === SANDBOX POSTS: timestamps + promotion to oracle-net ===
+		collection, err := app.FindCollectionByNameOrId("sandbox_posts")
+		if err != nil {
+			return err
+		}
+
+		if collection.Fields.GetByName("created") == nil {
+			collection.Fields.Add(&core.AutodateField{
+				Name:     "created",
+				OnCreate: true,
+			})
+		}
+		if collection.Fields.GetByName("updated") == nil {
+			collection.Fields.Add(&core.AutodateField{
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			})
+		}
+
+		// oracle-net post id once promoted
+		collection.Fields.Add(&core.TextField{
+			Name: "promoted_post",
+			Max:  15,
+		})
+		collection.Fields.Add(&core.DateField{
+			Name: "promoted_at",
+		})
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("sandbox_posts")
+		if err != nil {
+			return nil
+		}
+		collection.Fields.RemoveByName("promoted_post")
+		collection.Fields.RemoveByName("promoted_at")
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === AGENTS + HEARTBEATS: timestamps (reputation needs agent age and
+		// heartbeat history) ===
+		for _, name := range []string{"agents", "heartbeats"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+
+			if collection.Fields.GetByName("created") == nil {
+				collection.Fields.Add(&core.AutodateField{
+					Name:     "created",
+					OnCreate: true,
+				})
+			}
+			if collection.Fields.GetByName("updated") == nil {
+				collection.Fields.Add(&core.AutodateField{
+					Name:     "updated",
+					OnCreate: true,
+					OnUpdate: true,
+				})
+			}
+
+			if name == "agents" {
+				// Score components from the last reputation run
+				collection.Fields.Add(&core.JSONField{
+					Name:    "reputation_breakdown",
+					MaxSize: 1 << 12,
+				})
+				collection.Fields.Add(&core.DateField{
+					Name: "reputation_updated",
+				})
+				collection.AddIndex("idx_agents_reputation", false, "reputation", "")
+			} else {
+				collection.AddIndex("idx_heartbeats_agent_created", false, "agent, created", "")
+			}
+
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+
+			// Existing rows start their history now
+			_, err = app.DB().Update(name, dbx.Params{"created": types.NowDateTime().String(), "updated": types.NowDateTime().String()},
+				dbx.HashExp{"created": ""}).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("agents")
+		if err != nil {
+			return nil
+		}
+		collection.Fields.RemoveByName("reputation_breakdown")
+		collection.Fields.RemoveByName("reputation_updated")
+		collection.RemoveIndex("idx_agents_reputation")
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === HEARTBEATS: index for per-agent presence ===
+		collection, err := app.FindCollectionByNameOrId("heartbeats")
+		if err != nil {
+			return err
+		}
+
+		collection.AddIndex("idx_heartbeats_agent_updated", false, "agent, updated", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("heartbeats")
+		if err != nil {
+			return nil
+		}
+		collection.RemoveIndex("idx_heartbeats_agent_updated")
+		return app.Save(collection)
+	})
+}
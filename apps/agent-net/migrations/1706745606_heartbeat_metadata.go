@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		presence, err := app.FindCollectionByNameOrId("presence")
+		if err != nil {
+			return err
+		}
+
+		// Latest runtime snapshot reported with a heartbeat
+		presence.Fields.Add(&core.JSONField{
+			Name:    "metadata",
+			MaxSize: 1 << 14,
+		})
+
+		return app.Save(presence)
+	}, func(app core.App) error {
+		presence, err := app.FindCollectionByNameOrId("presence")
+		if err != nil {
+			return err
+		}
+		presence.Fields.RemoveByName("metadata")
+		return app.Save(presence)
+	})
+}
@@ -0,0 +1,1105 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"oracle-net/markdown"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	apContentType   = "application/activity+json"
+	apAccept        = `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	apPublic        = "https://www.w3.org/ns/activitystreams#Public"
+	maxActivitySize = 1 << 20
+	outboxPageSize  = 20
+)
+
+var apContext = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+var (
+	errApNotFound    = errors.New("Object not found")
+	errApBadActivity = errors.New("Malformed activity")
+	errApRemoteURL   = errors.New("Remote URL not allowed")
+)
+
+// apActivity is the subset of an incoming activity the inbox understands
+type apActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  json.RawMessage `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// apObject is the subset of a Note (or actor) the inbox reads
+type apObject struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	AttributedTo json.RawMessage `json:"attributedTo"`
+	InReplyTo    json.RawMessage `json:"inReplyTo"`
+	Content      string          `json:"content"`
+}
+
+// apActor is a remote actor document (or a bare key document)
+type apActor struct {
+	ID                string `json:"id"`
+	Type              string `json:"type"`
+	PreferredUsername string `json:"preferredUsername"`
+	Name              string `json:"name"`
+	URL               any    `json:"url"`
+	Inbox             string `json:"inbox"`
+	Endpoints         struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+
+	// Set when the keyId points at a standalone key document
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// apRef returns the id of a reference that is either a URI or an object
+func apRef(raw json.RawMessage) string {
+	var id string
+	if json.Unmarshal(raw, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		return object.ID
+	}
+	return ""
+}
+
+// htmlToText turns remote HTML content into plain text for the markdown pipeline
+func htmlToText(content string) string {
+	content = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n").Replace(content)
+	text := html.UnescapeString(bluemonday.StrictPolicy().Sanitize(content))
+	return strings.TrimSpace(text)
+}
+
+// oracleHandle is the WebFinger username of an oracle, matching @mentions
+func oracleHandle(oracle *core.Record) string {
+	name := oracle.GetString("oracle_name")
+	if name == "" {
+		name = oracle.GetString("name")
+	}
+	if name == "" {
+		return oracle.Id
+	}
+	return strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+}
+
+// remoteActorSummary is the author shape used for remote comments
+func remoteActorSummary(actor *core.Record) map[string]any {
+	return map[string]any{
+		"id":     actor.Id,
+		"name":   remoteActorName(actor),
+		"handle": "@" + actor.GetString("username") + "@" + actor.GetString("domain"),
+		"url":    actor.GetString("url"),
+		"remote": true,
+	}
+}
+
+func remoteActorName(actor *core.Record) string {
+	if name := actor.GetString("name"); name != "" {
+		return name
+	}
+	return "@" + actor.GetString("username") + "@" + actor.GetString("domain")
+}
+
+// remoteActorCache memoizes remote author lookups for the length of a request
+func remoteActorCache(app core.App) func(id string) map[string]any {
+	cache := map[string]map[string]any{}
+	return func(id string) map[string]any {
+		if id == "" {
+			return nil
+		}
+		if author, ok := cache[id]; ok {
+			return author
+		}
+		var author map[string]any
+		if actor, err := app.FindRecordById("remote_actors", id); err == nil {
+			author = remoteActorSummary(actor)
+		}
+		cache[id] = author
+		return author
+	}
+}
+
+// checkRemoteURL rejects non-https and private addresses, unless insecure
+// mode is on for a local test instance
+func checkRemoteURL(raw string, allowInsecure bool) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, errApRemoteURL
+	}
+	if allowInsecure {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, errApRemoteURL
+		}
+		return u, nil
+	}
+	if u.Scheme != "https" {
+		return nil, errApRemoteURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, errApRemoteURL
+	}
+	if ip := net.ParseIP(host); ip != nil && privateIP(ip) {
+		return nil, errApRemoteURL
+	}
+	return u, nil
+}
+
+// remoteClient returns an HTTP client for calls to other servers. Unless
+// insecure mode is on, it refuses to connect to private addresses, so
+// hostnames that resolve to them are stopped as well as literal IPs, and
+// every redirect target must pass checkRemoteURL too.
+func remoteClient(allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+				return errApRemoteURL
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			_, err := checkRemoteURL(req.URL.String(), allowInsecure)
+			return err
+		},
+	}
+}
+
+// privateIP reports whether an address is loopback, private or otherwise
+// not reachable from the public internet
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// federation holds the ActivityPub settings and HTTP client
+type federation struct {
+	app           core.App
+	client        *http.Client
+	allowInsecure bool
+	maxSkew       time.Duration
+}
+
+func (f *federation) base() string {
+	return apiBaseURL(f.app)
+}
+
+func (f *federation) actorURL(oracleId string) string {
+	return f.base() + "/ap/users/" + oracleId
+}
+
+// localObject maps one of our object URIs to a collection and record id
+func (f *federation) localObject(uri string) (collection string, id string) {
+	base := f.base()
+	for prefix, name := range map[string]string{
+		base + "/ap/posts/":          "posts",
+		base + "/ap/comments/":       "comments",
+		base + "/ap/users/":          "oracles",
+		webBaseURL(f.app) + "/post/": "posts",
+	} {
+		if rest, ok := strings.CutPrefix(uri, prefix); ok && rest != "" && !strings.Contains(rest, "/") {
+			return name, rest
+		}
+	}
+	return "", ""
+}
+
+// === KEYS ===
+
+// actorKey returns the signing key of an oracle, generating it on first use
+func (f *federation) actorKey(oracle *core.Record) (string, error) {
+	if key := oracle.GetString("ap_private_key"); key != "" {
+		return key, nil
+	}
+
+	privatePEM, publicPEM, err := generateKeyPair()
+	if err != nil {
+		return "", err
+	}
+
+	oracle.Set("ap_private_key", privatePEM)
+	oracle.Set("ap_public_key", publicPEM)
+	if err := f.app.Save(oracle); err != nil {
+		return "", err
+	}
+	return privatePEM, nil
+}
+
+// === REMOTE ACTORS ===
+
+func (f *federation) fetchJSON(raw string, out any) error {
+	u, err := checkRemoteURL(raw, f.allowInsecure)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", apAccept)
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", raw, res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxActivitySize)).Decode(out)
+}
+
+// fetchActor loads a remote actor, following a standalone key document to
+// its owner
+func (f *federation) fetchActor(uri string) (*apActor, error) {
+	uri, _, _ = strings.Cut(uri, "#")
+
+	actor := &apActor{}
+	if err := f.fetchJSON(uri, actor); err != nil {
+		return nil, err
+	}
+	if owner := actor.Owner; actor.Inbox == "" && owner != "" && owner != uri {
+		actor = &apActor{}
+		if err := f.fetchJSON(owner, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	if actor.ID == "" || actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return nil, errApBadActivity
+	}
+
+	// An actor can only speak for its own host
+	actorURL, err := url.Parse(actor.ID)
+	if err != nil {
+		return nil, errApBadActivity
+	}
+	for _, other := range []string{uri, actor.Inbox, actor.PublicKey.ID} {
+		if u, err := url.Parse(other); err != nil || u.Host != actorURL.Host {
+			return nil, errApBadActivity
+		}
+	}
+
+	return actor, nil
+}
+
+// storeRemoteActor creates or refreshes the remote_actors row of an actor
+func (f *federation) storeRemoteActor(actor *apActor) (*core.Record, error) {
+	record, err := f.app.FindFirstRecordByData("remote_actors", "actor_id", actor.ID)
+	if err != nil {
+		collection, err := f.app.FindCollectionByNameOrId("remote_actors")
+		if err != nil {
+			return nil, err
+		}
+		record = core.NewRecord(collection)
+	}
+
+	actorURL, _ := url.Parse(actor.ID)
+	profile, _ := actor.URL.(string)
+
+	record.Set("actor_id", actor.ID)
+	record.Set("username", actor.PreferredUsername)
+	record.Set("name", actor.Name)
+	record.Set("domain", actorURL.Host)
+	record.Set("url", profile)
+	record.Set("inbox", actor.Inbox)
+	record.Set("shared_inbox", actor.Endpoints.SharedInbox)
+	record.Set("public_key_id", actor.PublicKey.ID)
+	record.Set("public_key_pem", actor.PublicKey.PublicKeyPem)
+	record.Set("fetched_at", types.NowDateTime())
+
+	return record, f.app.Save(record)
+}
+
+// lookupKey resolves a signature keyId, from the cache unless refreshing
+func (f *federation) lookupKey(keyId string, refresh bool) (*rsa.PublicKey, string, error) {
+	var record *core.Record
+	if !refresh {
+		record, _ = f.app.FindFirstRecordByData("remote_actors", "public_key_id", keyId)
+	}
+
+	if record == nil {
+		actor, err := f.fetchActor(keyId)
+		if err != nil {
+			return nil, "", err
+		}
+		if actor.PublicKey.ID != keyId {
+			return nil, "", errSignatureInvalid
+		}
+		if record, err = f.storeRemoteActor(actor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	key, err := parsePublicKey(record.GetString("public_key_pem"))
+	if err != nil {
+		return nil, "", err
+	}
+	return key, record.GetString("actor_id"), nil
+}
+
+// === DOCUMENTS ===
+
+func (f *federation) actorDocument(oracle *core.Record) (map[string]any, error) {
+	if _, err := f.actorKey(oracle); err != nil {
+		return nil, err
+	}
+
+	actorURL := f.actorURL(oracle.Id)
+	name := oracle.GetString("oracle_name")
+	if name == "" {
+		name = oracle.GetString("name")
+	}
+
+	return map[string]any{
+		"@context":                  apContext,
+		"id":                        actorURL,
+		"type":                      "Service",
+		"preferredUsername":         oracleHandle(oracle),
+		"name":                      name,
+		"summary":                   html.EscapeString(oracle.GetString("bio")),
+		"url":                       webBaseURL(f.app) + "/oracles?id=" + oracle.Id,
+		"inbox":                     actorURL + "/inbox",
+		"outbox":                    actorURL + "/outbox",
+		"followers":                 actorURL + "/followers",
+		"manuallyApprovesFollowers": false,
+		"discoverable":              true,
+		"endpoints": map[string]string{
+			"sharedInbox": f.base() + "/ap/inbox",
+		},
+		"publicKey": map[string]string{
+			"id":           actorURL + "#main-key",
+			"owner":        actorURL,
+			"publicKeyPem": oracle.GetString("ap_public_key"),
+		},
+	}, nil
+}
+
+// postNote renders a post as a Note; the title leads the content since
+// Mastodon ignores "name" on Notes
+func (f *federation) postNote(post *core.Record) map[string]any {
+	actorURL := f.actorURL(post.GetString("author"))
+
+	tags := []map[string]string{}
+	for _, tag := range markdown.Hashtags(post.GetString("title") + "\n" + post.GetString("content")) {
+		tags = append(tags, map[string]string{
+			"type": "Hashtag",
+			"name": "#" + tag,
+			"href": webBaseURL(f.app) + "/tags/" + tag,
+		})
+	}
+
+	return map[string]any{
+		"id":           f.base() + "/ap/posts/" + post.Id,
+		"type":         "Note",
+		"attributedTo": actorURL,
+		"content":      "<p><strong>" + html.EscapeString(post.GetString("title")) + "</strong></p>" + post.GetString("content_html"),
+		"url":          webBaseURL(f.app) + "/post/" + post.Id,
+		"published":    publishTime(post).UTC().Format(time.RFC3339),
+		"to":           []string{apPublic},
+		"cc":           []string{actorURL + "/followers"},
+		"tag":          tags,
+	}
+}
+
+// commentNote renders a local or remote comment as a Note
+func (f *federation) commentNote(comment *core.Record) map[string]any {
+	if apId := comment.GetString("ap_id"); apId != "" {
+		return map[string]any{"id": apId, "type": "Note"}
+	}
+
+	inReplyTo := f.base() + "/ap/posts/" + comment.GetString("post")
+	if parent := comment.GetString("parent"); parent != "" {
+		inReplyTo = f.base() + "/ap/comments/" + parent
+		if record, err := f.app.FindRecordById("comments", parent); err == nil && record.GetString("ap_id") != "" {
+			inReplyTo = record.GetString("ap_id")
+		}
+	}
+
+	actorURL := f.actorURL(comment.GetString("author"))
+	return map[string]any{
+		"id":           f.base() + "/ap/comments/" + comment.Id,
+		"type":         "Note",
+		"attributedTo": actorURL,
+		"inReplyTo":    inReplyTo,
+		"content":      comment.GetString("content_html"),
+		"url":          webBaseURL(f.app) + "/post/" + comment.GetString("post") + "#comment-" + comment.Id,
+		"published":    recordTime(comment, "created").UTC().Format(time.RFC3339),
+		"to":           []string{apPublic},
+		"cc":           []string{actorURL + "/followers"},
+	}
+}
+
+func (f *federation) createActivity(post *core.Record) map[string]any {
+	note := f.postNote(post)
+	return map[string]any{
+		"id":        note["id"].(string) + "/activity",
+		"type":      "Create",
+		"actor":     note["attributedTo"],
+		"published": note["published"],
+		"to":        note["to"],
+		"cc":        note["cc"],
+		"object":    note,
+	}
+}
+
+// === DELIVERY ===
+
+// deliver POSTs a signed activity to a remote inbox
+func (f *federation) deliver(oracle *core.Record, inbox string, activity map[string]any) error {
+	u, err := checkRemoteURL(inbox, f.allowInsecure)
+	if err != nil {
+		return err
+	}
+
+	privatePEM, err := f.actorKey(oracle)
+	if err != nil {
+		return err
+	}
+	key, err := parsePrivateKey(privatePEM)
+	if err != nil {
+		return err
+	}
+
+	activity["@context"] = apContext
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", apContentType)
+	if err := signRequest(req, f.actorURL(oracle.Id)+"#main-key", key, body); err != nil {
+		return err
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxActivitySize))
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", inbox, res.Status)
+	}
+	return nil
+}
+
+// deliverLater sends an activity in the background, logging failures
+func (f *federation) deliverLater(oracle *core.Record, inboxes []string, activity map[string]any) {
+	go func() {
+		for _, inbox := range inboxes {
+			if err := f.deliver(oracle, inbox, activity); err != nil {
+				f.app.Logger().Warn("ActivityPub delivery failed", "inbox", inbox, "error", err)
+			}
+		}
+	}()
+}
+
+// followerInboxes lists the inboxes of an oracle's followers, preferring
+// shared inboxes so each server gets one copy
+func (f *federation) followerInboxes(oracleId string) []string {
+	var rows []struct {
+		Inbox       string `db:"inbox"`
+		SharedInbox string `db:"shared_inbox"`
+	}
+	err := f.app.DB().Select("remote_actors.inbox", "remote_actors.shared_inbox").
+		From("ap_followers").
+		InnerJoin("remote_actors", dbx.NewExp("remote_actors.id = ap_followers.actor")).
+		Where(dbx.HashExp{"ap_followers.oracle": oracleId}).
+		All(&rows)
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	inboxes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		inbox := row.SharedInbox
+		if inbox == "" {
+			inbox = row.Inbox
+		}
+		if !seen[inbox] {
+			seen[inbox] = true
+			inboxes = append(inboxes, inbox)
+		}
+	}
+	return inboxes
+}
+
+// === INBOX ===
+
+func (f *federation) handleInbox(re *core.RequestEvent) error {
+	body, err := io.ReadAll(io.LimitReader(re.Request.Body, maxActivitySize+1))
+	if err != nil || len(body) > maxActivitySize {
+		return re.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Activity too large"})
+	}
+
+	owner, err := verifyRequest(re.Request, body, f.maxSkew, f.lookupKey)
+	if err != nil {
+		return re.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	var activity apActivity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Type == "" {
+		return re.JSON(http.StatusBadRequest, map[string]string{"error": errApBadActivity.Error()})
+	}
+	if apRef(activity.Actor) != owner {
+		return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Actor does not match signing key"})
+	}
+
+	actor, err := f.app.FindFirstRecordByData("remote_actors", "actor_id", owner)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load actor"})
+	}
+
+	switch err := f.processActivity(&activity, actor); {
+	case errors.Is(err, errApNotFound):
+		return re.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, errApBadActivity):
+		return re.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		f.app.Logger().Warn("ActivityPub inbox failed", "activity", activity.ID, "type", activity.Type, "error", err)
+		return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process activity"})
+	}
+
+	return re.NoContent(http.StatusAccepted)
+}
+
+func (f *federation) processActivity(activity *apActivity, actor *core.Record) error {
+	switch activity.Type {
+	case "Follow":
+		return f.handleFollow(activity, actor)
+	case "Undo":
+		return f.handleUndo(activity, actor)
+	case "Like":
+		return f.handleLike(activity, actor)
+	case "Create":
+		return f.handleCreate(activity, actor)
+	}
+	// Other activity types are accepted and ignored
+	return nil
+}
+
+func (f *federation) handleFollow(activity *apActivity, actor *core.Record) error {
+	collection, id := f.localObject(apRef(activity.Object))
+	if collection != "oracles" {
+		return errApNotFound
+	}
+	oracle, err := f.app.FindRecordById("oracles", id)
+	if err != nil {
+		return errApNotFound
+	}
+
+	follower, err := f.app.FindFirstRecordByFilter("ap_followers",
+		"oracle = {:oracle} && actor = {:actor}",
+		dbx.Params{"oracle": oracle.Id, "actor": actor.Id},
+	)
+	isNew := err != nil
+	if isNew {
+		followers, err := f.app.FindCollectionByNameOrId("ap_followers")
+		if err != nil {
+			return err
+		}
+		follower = core.NewRecord(followers)
+		follower.Set("oracle", oracle.Id)
+		follower.Set("actor", actor.Id)
+	}
+	follower.Set("follow_id", activity.ID)
+	if err := f.app.Save(follower); err != nil {
+		return err
+	}
+
+	// Auto-accept (oracles don't approve followers manually)
+	f.deliverLater(oracle, []string{actor.GetString("inbox")}, map[string]any{
+		"id":    f.actorURL(oracle.Id) + "#accepts/" + follower.Id,
+		"type":  "Accept",
+		"actor": f.actorURL(oracle.Id),
+		"object": map[string]any{
+			"id":     activity.ID,
+			"type":   "Follow",
+			"actor":  actor.GetString("actor_id"),
+			"object": f.actorURL(oracle.Id),
+		},
+	})
+
+	if isNew {
+		if err := notify(f.app, notification{
+			Recipient: oracle.Id,
+			Type:      "follow",
+			Message:   remoteActorName(actor) + " followed you from " + actor.GetString("domain"),
+		}); err != nil {
+			f.app.Logger().Warn("Failed to send follow notification", "oracle", oracle.Id, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (f *federation) handleUndo(activity *apActivity, actor *core.Record) error {
+	var inner apActivity
+	if json.Unmarshal(activity.Object, &inner) != nil {
+		// A bare id: the undone activity is looked up by what it created
+		inner.ID = apRef(activity.Object)
+	}
+	if ref := apRef(inner.Actor); ref != "" && ref != actor.GetString("actor_id") {
+		return errApBadActivity
+	}
+
+	switch inner.Type {
+	case "Follow":
+		collection, id := f.localObject(apRef(inner.Object))
+		if collection != "oracles" {
+			return errApNotFound
+		}
+		return f.deleteWhere("ap_followers", "oracle = {:oracle} && actor = {:actor}",
+			dbx.Params{"oracle": id, "actor": actor.Id})
+	case "Like":
+		if err := f.deleteWhere("votes", "remote_voter = {:actor} && ap_id = {:id}",
+			dbx.Params{"actor": actor.Id, "id": inner.ID}); err != nil {
+			return err
+		}
+		targetType, targetId := f.voteTarget(apRef(inner.Object))
+		if targetType == "" {
+			return nil
+		}
+		if err := f.deleteWhere("votes", "remote_voter = {:actor} && target_type = {:type} && target_id = {:target}",
+			dbx.Params{"actor": actor.Id, "type": targetType, "target": targetId}); err != nil {
+			return err
+		}
+		return refreshVoteTotals(f.app, targetType, targetId)
+	case "":
+		if inner.ID == "" {
+			return errApBadActivity
+		}
+		if err := f.deleteWhere("ap_followers", "actor = {:actor} && follow_id = {:id}",
+			dbx.Params{"actor": actor.Id, "id": inner.ID}); err != nil {
+			return err
+		}
+		return f.deleteWhere("votes", "remote_voter = {:actor} && ap_id = {:id}",
+			dbx.Params{"actor": actor.Id, "id": inner.ID})
+	}
+
+	return nil
+}
+
+// deleteWhere deletes matching records through the app so hooks still run
+func (f *federation) deleteWhere(collection string, filter string, params dbx.Params) error {
+	records, err := f.app.FindRecordsByFilter(collection, filter, "", 0, 0, params)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := f.app.Delete(record); err != nil {
+			return err
+		}
+		if collection == "votes" {
+			if err := refreshVoteTotals(f.app, record.GetString("target_type"), record.GetString("target_id")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// voteTarget maps a liked object URI to a votes target
+func (f *federation) voteTarget(uri string) (targetType string, targetId string) {
+	switch collection, id := f.localObject(uri); collection {
+	case "posts":
+		return "post", id
+	case "comments":
+		return "comment", id
+	}
+	return "", ""
+}
+
+func (f *federation) handleLike(activity *apActivity, actor *core.Record) error {
+	targetType, targetId := f.voteTarget(apRef(activity.Object))
+	if targetType == "" {
+		return errApNotFound
+	}
+	if _, err := f.app.FindRecordById(targetType+"s", targetId); err != nil {
+		return errApNotFound
+	}
+
+	_, err := f.app.FindFirstRecordByFilter("votes",
+		"remote_voter = {:actor} && target_type = {:type} && target_id = {:target}",
+		dbx.Params{"actor": actor.Id, "type": targetType, "target": targetId},
+	)
+	if err == nil {
+		return nil // already liked
+	}
+
+	votes, err := f.app.FindCollectionByNameOrId("votes")
+	if err != nil {
+		return err
+	}
+	vote := core.NewRecord(votes)
+	vote.Set("remote_voter", actor.Id)
+	vote.Set("target_type", targetType)
+	vote.Set("target_id", targetId)
+	vote.Set("vote_type", "up")
+	vote.Set("ap_id", activity.ID)
+	if err := f.app.Save(vote); err != nil {
+		return err
+	}
+
+	return refreshVoteTotals(f.app, targetType, targetId)
+}
+
+func (f *federation) handleCreate(activity *apActivity, actor *core.Record) error {
+	var note apObject
+	if err := json.Unmarshal(activity.Object, &note); err != nil || note.ID == "" {
+		return errApBadActivity
+	}
+	if note.Type != "Note" || apRef(note.AttributedTo) != actor.GetString("actor_id") {
+		return errApBadActivity
+	}
+
+	// Only replies to our posts and comments are stored
+	collection, id := f.localObject(apRef(note.InReplyTo))
+	var postId, parentId string
+	switch collection {
+	case "posts":
+		postId = id
+	case "comments":
+		parent, err := f.app.FindRecordById("comments", id)
+		if err != nil {
+			return errApNotFound
+		}
+		postId, parentId = parent.GetString("post"), parent.Id
+	default:
+		ref := apRef(note.InReplyTo)
+		if ref == "" {
+			return nil
+		}
+		parent, err := f.app.FindFirstRecordByData("comments", "ap_id", ref)
+		if err != nil {
+			return nil
+		}
+		postId, parentId = parent.GetString("post"), parent.Id
+	}
+	post, err := f.app.FindRecordById("posts", postId)
+	if err != nil {
+		return errApNotFound
+	}
+	if post.GetBool("locked") || post.GetString("moderation") != "" || !isPublished(post) {
+		return nil // closed to replies
+	}
+
+	if _, err := f.app.FindFirstRecordByData("comments", "ap_id", note.ID); err == nil {
+		return nil // already stored
+	}
+
+	content := htmlToText(note.Content)
+	if content == "" {
+		return errApBadActivity
+	}
+	if runes := []rune(content); len(runes) > 5000 {
+		content = string(runes[:5000])
+	}
+
+	comments, err := f.app.FindCollectionByNameOrId("comments")
+	if err != nil {
+		return err
+	}
+	comment := core.NewRecord(comments)
+	comment.Set("post", postId)
+	comment.Set("parent", parentId)
+	comment.Set("content", content)
+	comment.Set("remote_author", actor.Id)
+	comment.Set("ap_id", note.ID)
+	comment.Set("upvotes", 0)
+	comment.Set("downvotes", 0)
+	if err := validateCommentParent(f.app, comment, envInt("COMMENT_MAX_DEPTH", 8)); err != nil {
+		return errApBadActivity
+	}
+
+	return f.app.Save(comment)
+}
+
+// refreshVoteTotals recomputes the denormalized vote counts of a post or comment
+func refreshVoteTotals(app core.App, targetType string, targetId string) error {
+	table := map[string]string{"post": "posts", "comment": "comments"}[targetType]
+	if table == "" {
+		return nil
+	}
+
+	set := "upvotes = {:up}, downvotes = {:down}"
+	if table == "posts" {
+		set += ", score = {:up} - {:down}"
+	}
+
+	counts := map[string]int64{}
+	for _, voteType := range []string{"up", "down"} {
+		count, err := app.CountRecords("votes", dbx.HashExp{
+			"target_type": targetType,
+			"target_id":   targetId,
+			"vote_type":   voteType,
+		})
+		if err != nil {
+			return err
+		}
+		counts[voteType] = count
+	}
+
+	_, err := app.DB().NewQuery("UPDATE " + table + " SET " + set + " WHERE id = {:id}").
+		Bind(dbx.Params{"up": counts["up"], "down": counts["down"], "id": targetId}).
+		Execute()
+	return err
+}
+
+// === ROUTES ===
+
+// RegisterActivityPub exposes oracles as ActivityPub actors: WebFinger, actor
+// documents, outboxes and a signed inbox for Follow/Undo/Like/Create replies
+func RegisterActivityPub(app *pocketbase.PocketBase) {
+	allowInsecure := envInt("AP_ALLOW_INSECURE", 0) == 1
+	f := &federation{
+		app:           app,
+		client:        remoteClient(allowInsecure),
+		allowInsecure: allowInsecure,
+		maxSkew:       time.Duration(envInt("AP_SIGNATURE_MAX_AGE_MINUTES", 60)) * time.Minute,
+	}
+
+	// Remote fields are only written by the inbox
+	app.OnRecordCreateRequest("comments", "votes").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.HasSuperuserAuth() {
+			return e.Next()
+		}
+		e.Record.Set("ap_id", "")
+		if e.Record.Collection().Name == "comments" {
+			e.Record.Set("remote_author", "")
+		} else {
+			e.Record.Set("remote_voter", "")
+		}
+		return e.Next()
+	})
+
+	// Posts: push posts to remote followers when they go live
+	deliverPost := func(e *core.RecordEvent) error {
+		authorId := e.Record.GetString("author")
+		if inboxes := f.followerInboxes(authorId); len(inboxes) > 0 {
+			if oracle, err := e.App.FindRecordById("oracles", authorId); err == nil {
+				f.deliverLater(oracle, inboxes, f.createActivity(e.Record))
+			}
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if !isPublished(e.Record) {
+			return e.Next()
+		}
+		return deliverPost(e)
+	})
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if !justPublished(e.Record) {
+			return e.Next()
+		}
+		return deliverPost(e)
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// WebFinger: acct:handle@host -> actor
+		e.Router.GET("/.well-known/webfinger", func(re *core.RequestEvent) error {
+			resource := re.Request.URL.Query().Get("resource")
+
+			var oracle *core.Record
+			if collection, id := f.localObject(resource); collection == "oracles" {
+				oracle, _ = app.FindRecordById("oracles", id)
+			} else if acct, ok := strings.CutPrefix(resource, "acct:"); ok {
+				handle, host, _ := strings.Cut(strings.TrimPrefix(acct, "@"), "@")
+				if base, err := url.Parse(f.base()); err == nil && strings.EqualFold(host, base.Host) {
+					if found := findMentionedOracles(app, []string{strings.ToLower(handle)}); len(found) > 0 {
+						oracle = found[0]
+					} else {
+						oracle, _ = app.FindRecordById("oracles", handle)
+					}
+				}
+			}
+			if oracle == nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Resource not found"})
+			}
+
+			base, _ := url.Parse(f.base())
+			data, err := json.Marshal(map[string]any{
+				"subject": "acct:" + oracleHandle(oracle) + "@" + base.Host,
+				"aliases": []string{f.actorURL(oracle.Id)},
+				"links": []map[string]string{
+					{"rel": "self", "type": apContentType, "href": f.actorURL(oracle.Id)},
+					{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": webBaseURL(app) + "/oracles?id=" + oracle.Id},
+				},
+			})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to render resource"})
+			}
+			return re.Blob(http.StatusOK, "application/jrd+json; charset=utf-8", data)
+		})
+
+		// Actor document
+		e.Router.GET("/ap/users/{id}", func(re *core.RequestEvent) error {
+			oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Oracle not found"})
+			}
+			doc, err := f.actorDocument(oracle)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load actor key"})
+			}
+			return apJSON(re, doc)
+		})
+
+		// Outbox: Create activities for the oracle's posts, newest first
+		e.Router.GET("/ap/users/{id}/outbox", func(re *core.RequestEvent) error {
+			oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Oracle not found"})
+			}
+
+			outboxURL := f.actorURL(oracle.Id) + "/outbox"
+			total, _ := app.CountRecords("posts", dbx.HashExp{"author": oracle.Id, "moderation": "", "status": "published"})
+
+			if re.Request.URL.Query().Get("page") == "" {
+				return apJSON(re, map[string]any{
+					"@context":   apContext,
+					"id":         outboxURL,
+					"type":       "OrderedCollection",
+					"totalItems": total,
+					"first":      outboxURL + "?page=1",
+				})
+			}
+
+			page := queryInt(re, "page", 1, 1, 100000)
+			posts, err := app.FindRecordsByFilter("posts", publishedFilter("author = {:author}"), "-created",
+				outboxPageSize, (page-1)*outboxPageSize, dbx.Params{"author": oracle.Id})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load outbox"})
+			}
+
+			items := make([]map[string]any, 0, len(posts))
+			for _, post := range posts {
+				items = append(items, f.createActivity(post))
+			}
+
+			doc := map[string]any{
+				"@context":     apContext,
+				"id":           fmt.Sprintf("%s?page=%d", outboxURL, page),
+				"type":         "OrderedCollectionPage",
+				"partOf":       outboxURL,
+				"orderedItems": items,
+			}
+			if int64(page*outboxPageSize) < total {
+				doc["next"] = fmt.Sprintf("%s?page=%d", outboxURL, page+1)
+			}
+			if page > 1 {
+				doc["prev"] = fmt.Sprintf("%s?page=%d", outboxURL, page-1)
+			}
+			return apJSON(re, doc)
+		})
+
+		// Followers: count only, the list stays private
+		e.Router.GET("/ap/users/{id}/followers", func(re *core.RequestEvent) error {
+			oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Oracle not found"})
+			}
+			total, _ := app.CountRecords("ap_followers", dbx.HashExp{"oracle": oracle.Id})
+			return apJSON(re, map[string]any{
+				"@context":   apContext,
+				"id":         f.actorURL(oracle.Id) + "/followers",
+				"type":       "OrderedCollection",
+				"totalItems": total,
+			})
+		})
+
+		// Objects
+		e.Router.GET("/ap/posts/{id}", func(re *core.RequestEvent) error {
+			post, err := app.FindRecordById("posts", re.Request.PathValue("id"))
+			if err != nil || post.GetString("moderation") != "" || !isPublished(post) {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+			}
+			note := f.postNote(post)
+			note["@context"] = apContext
+			return apJSON(re, note)
+		})
+		e.Router.GET("/ap/comments/{id}", func(re *core.RequestEvent) error {
+			comment, err := app.FindRecordById("comments", re.Request.PathValue("id"))
+			if err != nil || comment.GetString("ap_id") != "" || comment.GetString("moderation") != "" {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Comment not found"})
+			}
+			note := f.commentNote(comment)
+			note["@context"] = apContext
+			return apJSON(re, note)
+		})
+
+		// Inboxes (per actor and shared)
+		e.Router.POST("/ap/users/{id}/inbox", f.handleInbox)
+		e.Router.POST("/ap/inbox", f.handleInbox)
+
+		return e.Next()
+	})
+}
+
+func apJSON(re *core.RequestEvent, doc map[string]any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to render document"})
+	}
+	return re.Blob(http.StatusOK, apContentType+"; charset=utf-8", data)
+}
@@ -0,0 +1,491 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteInstance plays a remote ActivityPub server: it serves one actor with
+// a signing key and records the activities delivered to its inbox
+type remoteInstance struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	received []map[string]any
+	verify   publicKeyLookup
+}
+
+func newRemoteInstance(t *testing.T) *remoteInstance {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ri := &remoteInstance{t: t, key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", apContentType)
+		json.NewEncoder(w).Encode(ri.actorDocument())
+	})
+	mux.HandleFunc("POST /users/alice/inbox", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if ri.verify != nil {
+			if _, err := verifyRequest(r, body, time.Minute, ri.verify); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		var activity map[string]any
+		json.Unmarshal(body, &activity)
+		ri.mu.Lock()
+		ri.received = append(ri.received, activity)
+		ri.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ri.server = httptest.NewServer(mux)
+	t.Cleanup(ri.server.Close)
+
+	return ri
+}
+
+func (ri *remoteInstance) actorURL() string {
+	return ri.server.URL + "/users/alice"
+}
+
+func (ri *remoteInstance) actorDocument() map[string]any {
+	der, _ := x509.MarshalPKIXPublicKey(&ri.key.PublicKey)
+	return map[string]any{
+		"@context":          apContext,
+		"id":                ri.actorURL(),
+		"type":              "Person",
+		"preferredUsername": "alice",
+		"name":              "Alice Remote",
+		"url":               ri.server.URL + "/@alice",
+		"inbox":             ri.actorURL() + "/inbox",
+		"publicKey": map[string]string{
+			"id":           ri.actorURL() + "#main-key",
+			"owner":        ri.actorURL(),
+			"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		},
+	}
+}
+
+// signedPost builds a signed inbox request carrying activity
+func (ri *remoteInstance) signedPost(target string, activity map[string]any) (*http.Request, []byte) {
+	ri.t.Helper()
+	body, _ := json.Marshal(activity)
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		ri.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", apContentType)
+	if err := signRequest(req, ri.actorURL()+"#main-key", ri.key, body); err != nil {
+		ri.t.Fatal(err)
+	}
+	return req, body
+}
+
+func (ri *remoteInstance) activities() []map[string]any {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	return append([]map[string]any(nil), ri.received...)
+}
+
+// fetchingLookup resolves keys by fetching actors, like the inbox does,
+// without the remote_actors cache
+func fetchingLookup(f *federation) publicKeyLookup {
+	return func(keyId string, refresh bool) (*rsa.PublicKey, string, error) {
+		actor, err := f.fetchActor(keyId)
+		if err != nil {
+			return nil, "", err
+		}
+		key, err := parsePublicKey(actor.PublicKey.PublicKeyPem)
+		return key, actor.ID, err
+	}
+}
+
+// asServerRequest turns a client request into what a handler receives
+func asServerRequest(req *http.Request, body []byte) *http.Request {
+	server := httptest.NewRequest(req.Method, req.URL.String(), bytes.NewReader(body))
+	server.Header = req.Header.Clone()
+	server.Host = req.Host
+	return server
+}
+
+func TestVerifyRequestFromRemoteInstance(t *testing.T) {
+	ri := newRemoteInstance(t)
+	f := &federation{client: ri.server.Client(), allowInsecure: true}
+	lookup := fetchingLookup(f)
+
+	follow := map[string]any{
+		"id":     ri.actorURL() + "#follows/1",
+		"type":   "Follow",
+		"actor":  ri.actorURL(),
+		"object": "https://oracle.example/ap/users/abc",
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		req, body := ri.signedPost("https://oracle.example/ap/inbox", follow)
+		owner, err := verifyRequest(asServerRequest(req, body), body, time.Minute, lookup)
+		if err != nil {
+			t.Fatalf("expected valid signature, got %v", err)
+		}
+		if owner != ri.actorURL() {
+			t.Errorf("owner = %q, expected %q", owner, ri.actorURL())
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req, body := ri.signedPost("https://oracle.example/ap/inbox", follow)
+		tampered := bytes.Replace(body, []byte("Follow"), []byte("Block"), 1)
+		if _, err := verifyRequest(asServerRequest(req, tampered), tampered, time.Minute, lookup); err != errDigestMismatch {
+			t.Errorf("expected digest mismatch, got %v", err)
+		}
+	})
+
+	t.Run("other target", func(t *testing.T) {
+		req, body := ri.signedPost("https://oracle.example/ap/inbox", follow)
+		server := asServerRequest(req, body)
+		server.URL.Path = "/ap/users/other/inbox"
+		if _, err := verifyRequest(server, body, time.Minute, lookup); err != errSignatureInvalid {
+			t.Errorf("expected invalid signature, got %v", err)
+		}
+	})
+
+	t.Run("stale date", func(t *testing.T) {
+		req, body := ri.signedPost("https://oracle.example/ap/inbox", follow)
+		server := asServerRequest(req, body)
+		server.Header.Set("Date", time.Now().Add(-2*time.Hour).UTC().Format(http.TimeFormat))
+		if _, err := verifyRequest(server, body, time.Hour, lookup); err != errSignatureExpired {
+			t.Errorf("expected expired signature, got %v", err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		req, body := ri.signedPost("https://oracle.example/ap/inbox", follow)
+		server := asServerRequest(req, body)
+		server.Header.Del("Signature")
+		if _, err := verifyRequest(server, body, time.Minute, lookup); err != errSignatureMissing {
+			t.Errorf("expected missing signature, got %v", err)
+		}
+	})
+}
+
+func TestSignedDeliveryToRemoteInstance(t *testing.T) {
+	ri := newRemoteInstance(t)
+
+	privatePEM, publicPEM, err := generateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := parsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ri.verify = func(keyId string, refresh bool) (*rsa.PublicKey, string, error) {
+		pub, err := parsePublicKey(publicPEM)
+		return pub, "https://oracle.example/ap/users/abc", err
+	}
+
+	body := []byte(`{"type":"Accept"}`)
+	req, _ := http.NewRequest(http.MethodPost, ri.actorURL()+"/inbox", bytes.NewReader(body))
+	if err := signRequest(req, "https://oracle.example/ap/users/abc#main-key", key, body); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ri.server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("remote rejected delivery: %s", res.Status)
+	}
+	if got := ri.activities(); len(got) != 1 || got[0]["type"] != "Accept" {
+		t.Errorf("unexpected deliveries: %v", got)
+	}
+}
+
+func TestFetchActorRejectsForeignKeys(t *testing.T) {
+	ri := newRemoteInstance(t)
+	f := &federation{client: ri.server.Client(), allowInsecure: true}
+
+	if _, err := f.fetchActor(ri.actorURL() + "#main-key"); err != nil {
+		t.Fatalf("expected actor to load, got %v", err)
+	}
+
+	// An actor document claiming another host's inbox is refused
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/mallory", func(w http.ResponseWriter, r *http.Request) {
+		doc := ri.actorDocument()
+		doc["id"] = "http://" + r.Host + "/users/mallory"
+		json.NewEncoder(w).Encode(doc)
+	})
+	other := httptest.NewServer(mux)
+	defer other.Close()
+
+	if _, err := f.fetchActor(other.URL + "/users/mallory"); err == nil {
+		t.Error("expected actor with foreign inbox to be rejected")
+	}
+}
+
+func TestCheckRemoteURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		insecure bool
+		ok       bool
+	}{
+		{"https://mastodon.social/users/alice", false, true},
+		{"http://mastodon.social/users/alice", false, false},
+		{"https://127.0.0.1/users/alice", false, false},
+		{"https://10.0.0.5/users/alice", false, false},
+		{"https://localhost/users/alice", false, false},
+		{"https://LocalHost/users/alice", false, false},
+		{"https://localhost./users/alice", false, false},
+		{"http://127.0.0.1:9000/users/alice", true, true},
+		{"ftp://example.com/x", true, false},
+		{"not a url", false, false},
+	}
+
+	for _, tt := range tests {
+		if _, err := checkRemoteURL(tt.url, tt.insecure); (err == nil) != tt.ok {
+			t.Errorf("checkRemoteURL(%q, %v) error = %v, expected ok=%v", tt.url, tt.insecure, err, tt.ok)
+		}
+	}
+}
+
+func TestRemoteClientRefusesPrivateHosts(t *testing.T) {
+	// Counts connections, so a refused request can't be confused with one
+	// that reached the server and failed afterwards
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// Hostnames that resolve to loopback are refused when connecting
+	client := remoteClient(false)
+	for _, target := range []string{"https://localhost:" + port + "/users/alice", "http://localhost:" + port + "/"} {
+		if resp, err := client.Get(target); err == nil {
+			resp.Body.Close()
+			t.Errorf("expected a request to %q to fail", target)
+		}
+	}
+	if n := accepted.Load(); n != 0 {
+		t.Errorf("expected no connections to the loopback listener, got %d", n)
+	}
+
+	// Redirects must lead to public https URLs too
+	for target, ok := range map[string]bool{
+		"https://mastodon.social/users/alice": true,
+		"http://mastodon.social/users/alice":  false,
+		"https://169.254.169.254/latest":      false,
+		"https://localhost/admin":             false,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		if err := client.CheckRedirect(req, nil); (err == nil) != ok {
+			t.Errorf("redirect to %q: error = %v, expected ok=%v", target, err, ok)
+		}
+	}
+
+	// Insecure mode is for local test instances
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	resp, err := remoteClient(true).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected a local request in insecure mode, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHtmlToText(t *testing.T) {
+	input := `<p><span class="h-card"><a href="https://oracle.example/ap/users/abc">@<span>shrimp</span></a></span> nice post &amp; thanks</p><p>second<br>line<script>x</script></p>`
+	expected := "@shrimp nice post & thanks\n\nsecond\nline"
+	if got := htmlToText(input); got != expected {
+		t.Errorf("htmlToText = %q, expected %q", got, expected)
+	}
+}
+
+// TestFederationHarness runs the remote instance against a live server:
+//
+//	AP_ALLOW_INSECURE=1 ./oracle-net serve
+//	ORACLE_NET_URL=http://127.0.0.1:8090 ORACLE_NET_ORACLE=<handle> go test ./hooks -run Harness -v
+//
+// The oracle needs at least one post.
+func TestFederationHarness(t *testing.T) {
+	base := os.Getenv("ORACLE_NET_URL")
+	handle := os.Getenv("ORACLE_NET_ORACLE")
+	if base == "" || handle == "" {
+		t.Skip("ORACLE_NET_URL and ORACLE_NET_ORACLE not set")
+	}
+
+	ri := newRemoteInstance(t)
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	getJSON := func(path string, out any) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", apAccept)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %s", path, res.Status)
+		}
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliver := func(inbox string, activity map[string]any) {
+		t.Helper()
+		req, _ := ri.signedPost(inbox, activity)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("%s rejected: %s %s", activity["type"], res.Status, msg)
+		}
+	}
+
+	// WebFinger -> actor
+	host, _ := url.Parse(base)
+	var finger struct {
+		Links []struct{ Rel, Type, Href string }
+	}
+	getJSON(base+"/.well-known/webfinger?resource=acct:"+handle+"@"+host.Host, &finger)
+	var actorURL string
+	for _, link := range finger.Links {
+		if link.Rel == "self" {
+			actorURL = link.Href
+		}
+	}
+
+	var actor struct {
+		ID        string
+		Inbox     string
+		Outbox    string
+		PublicKey struct{ PublicKeyPem string }
+	}
+	getJSON(actorURL, &actor)
+	ri.verify = func(string, bool) (*rsa.PublicKey, string, error) {
+		key, err := parsePublicKey(actor.PublicKey.PublicKeyPem)
+		return key, actor.ID, err
+	}
+
+	// Follow -> signed Accept arrives
+	followId := ri.actorURL() + "#follows/1"
+	deliver(actor.Inbox, map[string]any{"id": followId, "type": "Follow", "actor": ri.actorURL(), "object": actor.ID})
+	waitFor(t, "Accept", func() bool {
+		for _, a := range ri.activities() {
+			if a["type"] == "Accept" {
+				return true
+			}
+		}
+		return false
+	})
+
+	// Outbox -> newest post
+	var page struct {
+		OrderedItems []struct {
+			Object struct{ ID string }
+		}
+	}
+	getJSON(actor.Outbox+"?page=1", &page)
+	if len(page.OrderedItems) == 0 {
+		t.Fatal("outbox is empty; create a post first")
+	}
+	noteId := page.OrderedItems[0].Object.ID
+	postId := noteId[strings.LastIndex(noteId, "/")+1:]
+
+	// Like + reply land as a vote and a comment
+	likeId := ri.actorURL() + "#likes/1"
+	deliver(base+"/ap/inbox", map[string]any{"id": likeId, "type": "Like", "actor": ri.actorURL(), "object": noteId})
+
+	replyId := ri.actorURL() + "/statuses/1"
+	deliver(base+"/ap/inbox", map[string]any{
+		"id": replyId + "/activity", "type": "Create", "actor": ri.actorURL(),
+		"object": map[string]any{
+			"id": replyId, "type": "Note", "attributedTo": ri.actorURL(),
+			"inReplyTo": noteId, "content": "<p>Hello from the <b>fediverse</b></p>",
+		},
+	})
+
+	var thread struct {
+		Comments []map[string]any
+	}
+	getJSON(base+"/api/posts/"+postId+"/comments?sort=new", &thread)
+	found := false
+	for _, c := range thread.Comments {
+		author, _ := c["author"].(map[string]any)
+		if c["content"] == "Hello from the fediverse" && author != nil && author["remote"] == true {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("remote reply not found in thread: %v", thread.Comments)
+	}
+
+	// Undo both
+	deliver(base+"/ap/inbox", map[string]any{
+		"id": likeId + "/undo", "type": "Undo", "actor": ri.actorURL(),
+		"object": map[string]any{"id": likeId, "type": "Like", "actor": ri.actorURL(), "object": noteId},
+	})
+	deliver(actor.Inbox, map[string]any{
+		"id": followId + "/undo", "type": "Undo", "actor": ri.actorURL(),
+		"object": map[string]any{"id": followId, "type": "Follow", "actor": ri.actorURL(), "object": actor.ID},
+	})
+
+	var followers struct{ TotalItems int }
+	getJSON(actor.ID+"/followers", &followers)
+	if followers.TotalItems != 0 {
+		t.Errorf("expected follow to be undone, %d followers left", followers.TotalItems)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
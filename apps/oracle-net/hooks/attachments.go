@@ -0,0 +1,244 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// attachmentThumbSize is generated for every image attachment on upload
+// (and listed in the field's thumbs so PocketBase serves it)
+const attachmentThumbSize = "480x0"
+
+var (
+	errAttachmentType   = errors.New("Attachment type is not allowed")
+	errAttachmentSize   = errors.New("Attachment is too large")
+	errAttachmentPixels = errors.New("Attachment image dimensions are too large")
+)
+
+// attachmentMeta describes one stored file of posts.attachments
+type attachmentMeta struct {
+	Name         string `json:"name"`
+	OriginalName string `json:"original_name"`
+	Mime         string `json:"mime"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// attachmentConfig holds the configured upload limits
+type attachmentConfig struct {
+	Allowed   []string
+	MaxSize   int64
+	MaxPixels int
+}
+
+// checkAttachment detects the real type of an upload from its content and
+// checks it against the allowlist and limits
+func checkAttachment(data []byte, cfg attachmentConfig) (attachmentMeta, error) {
+	if int64(len(data)) > cfg.MaxSize {
+		return attachmentMeta{}, errAttachmentSize
+	}
+
+	detected := mimetype.Detect(data)
+	if !slices.ContainsFunc(cfg.Allowed, detected.Is) {
+		return attachmentMeta{}, errAttachmentType
+	}
+
+	mime, _, _ := strings.Cut(detected.String(), ";")
+	meta := attachmentMeta{Mime: mime, Size: int64(len(data))}
+
+	if strings.HasPrefix(mime, "image/") {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			if config.Width*config.Height > cfg.MaxPixels {
+				return attachmentMeta{}, errAttachmentPixels
+			}
+			meta.Width, meta.Height = config.Width, config.Height
+		}
+	}
+
+	return meta, nil
+}
+
+// stripImageMetadata re-encodes a JPEG or PNG without its EXIF and other
+// metadata, applying the EXIF orientation first so the image stays upright
+func stripImageMetadata(data []byte, mime string) ([]byte, int, int, error) {
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	format := imaging.PNG
+	if mime == "image/jpeg" {
+		format = imaging.JPEG
+	}
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(90)); err != nil {
+		return nil, 0, 0, err
+	}
+
+	bounds := img.Bounds()
+	return buf.Bytes(), bounds.Dx(), bounds.Dy(), nil
+}
+
+// processAttachment validates an upload and replaces its content with a
+// metadata-free copy where the format allows it
+func processAttachment(file *filesystem.File, cfg attachmentConfig) (attachmentMeta, error) {
+	reader, err := file.Reader.Open()
+	if err != nil {
+		return attachmentMeta{}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, cfg.MaxSize+1))
+	reader.Close()
+	if err != nil {
+		return attachmentMeta{}, err
+	}
+
+	meta, err := checkAttachment(data, cfg)
+	if err != nil {
+		return attachmentMeta{}, err
+	}
+
+	if meta.Mime == "image/jpeg" || meta.Mime == "image/png" {
+		stripped, width, height, err := stripImageMetadata(data, meta.Mime)
+		if err != nil {
+			return attachmentMeta{}, errAttachmentType
+		}
+		file.Reader = &filesystem.BytesReader{Bytes: stripped}
+		file.Size = int64(len(stripped))
+		meta.Size, meta.Width, meta.Height = file.Size, width, height
+	}
+
+	meta.Name = file.Name
+	meta.OriginalName = file.OriginalName
+	return meta, nil
+}
+
+// syncAttachments processes new uploads and rebuilds attachments_meta in the
+// order of the attachments field, dropping entries of removed files
+func syncAttachments(record *core.Record, previous *core.Record, cfg attachmentConfig) error {
+	known := map[string]attachmentMeta{}
+	if previous != nil {
+		var metas []attachmentMeta
+		_ = previous.UnmarshalJSONField("attachments_meta", &metas)
+		for _, meta := range metas {
+			known[meta.Name] = meta
+		}
+	}
+
+	files, _ := record.GetRaw("attachments").([]any)
+	metas := make([]attachmentMeta, 0, len(files))
+	for _, value := range files {
+		switch file := value.(type) {
+		case string:
+			meta, ok := known[file]
+			if !ok {
+				meta = attachmentMeta{Name: file}
+			}
+			metas = append(metas, meta)
+		case *filesystem.File:
+			meta, err := processAttachment(file, cfg)
+			if err != nil {
+				return err
+			}
+			metas = append(metas, meta)
+		}
+	}
+
+	record.Set("attachments_meta", metas)
+	return nil
+}
+
+// attachmentItems serializes a post's attachments with their file URLs
+func attachmentItems(app core.App, record *core.Record) []map[string]any {
+	var metas []attachmentMeta
+	_ = record.UnmarshalJSONField("attachments_meta", &metas)
+
+	base := apiBaseURL(app) + "/api/files/" + record.Collection().Id + "/" + record.Id + "/"
+	items := make([]map[string]any, 0, len(metas))
+	for _, meta := range metas {
+		item := map[string]any{
+			"name":          meta.Name,
+			"original_name": meta.OriginalName,
+			"mime":          meta.Mime,
+			"size":          meta.Size,
+			"width":         meta.Width,
+			"height":        meta.Height,
+			"url":           base + meta.Name,
+			"thumb_url":     nil,
+		}
+		if meta.Width > 0 {
+			item["thumb_url"] = base + meta.Name + "?thumb=" + attachmentThumbSize
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// createAttachmentThumbs generates missing thumbnails for image attachments
+func createAttachmentThumbs(app core.App, record *core.Record) error {
+	var metas []attachmentMeta
+	_ = record.UnmarshalJSONField("attachments_meta", &metas)
+
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	for _, meta := range metas {
+		if meta.Width == 0 {
+			continue
+		}
+		thumb := record.BaseFilesPath() + "/thumbs_" + meta.Name + "/" + attachmentThumbSize + "_" + meta.Name
+		if exists, _ := fsys.Exists(thumb); exists {
+			continue
+		}
+		if err := fsys.CreateThumb(record.BaseFilesPath()+"/"+meta.Name, thumb, attachmentThumbSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterAttachments validates, strips and describes post attachments and
+// pre-generates their thumbnails
+func RegisterAttachments(app *pocketbase.PocketBase) {
+	cfg := attachmentConfig{
+		Allowed:   strings.Split(envString("ATTACHMENT_MIME_TYPES", "image/png,image/jpeg,image/gif"), ","),
+		MaxSize:   int64(envInt("ATTACHMENT_MAX_MB", 5)) << 20,
+		MaxPixels: envInt("ATTACHMENT_MAX_PIXELS", 40_000_000),
+	}
+
+	app.OnRecordCreateRequest("posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := syncAttachments(e.Record, nil, cfg); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdateRequest("posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := syncAttachments(e.Record, e.Record.Original(), cfg); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	thumbs := func(e *core.RecordEvent) error {
+		if err := createAttachmentThumbs(e.App, e.Record); err != nil {
+			e.App.Logger().Warn("Failed to create attachment thumbnails", "post", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(thumbs)
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(thumbs)
+}
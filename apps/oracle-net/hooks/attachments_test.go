@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 Exif segment after the JPEG SOI marker
+func withExif(data []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 13.7563 N 100.5018 E")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestCheckAttachment(t *testing.T) {
+	cfg := attachmentConfig{Allowed: []string{"image/png", "image/jpeg"}, MaxSize: 1 << 20, MaxPixels: 10000}
+
+	meta, err := checkAttachment(testImage(t, "png", 40, 20), cfg)
+	if err != nil || meta.Mime != "image/png" || meta.Width != 40 || meta.Height != 20 {
+		t.Errorf("unexpected %+v, %v", meta, err)
+	}
+
+	if _, err := checkAttachment([]byte("#!/bin/sh\necho hi\n"), cfg); err != errAttachmentType {
+		t.Errorf("expected a type error, got %v", err)
+	}
+	if _, err := checkAttachment(testImage(t, "png", 200, 100), cfg); err != errAttachmentPixels {
+		t.Errorf("expected a dimensions error, got %v", err)
+	}
+
+	cfg.MaxSize = 10
+	if _, err := checkAttachment(testImage(t, "png", 4, 4), cfg); err != errAttachmentSize {
+		t.Errorf("expected a size error, got %v", err)
+	}
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	original := withExif(testImage(t, "jpeg", 16, 8))
+	if !bytes.Contains(original, []byte("Exif")) {
+		t.Fatal("expected the fixture to carry EXIF")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(original)); err != nil {
+		t.Fatalf("fixture is not a valid JPEG: %v", err)
+	}
+
+	stripped, width, height, err := stripImageMetadata(original, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("Exif")) || bytes.Contains(stripped, []byte("GPS")) {
+		t.Error("expected EXIF to be removed")
+	}
+	if width != 16 || height != 8 {
+		t.Errorf("expected 16x8, got %dx%d", width, height)
+	}
+}
@@ -0,0 +1,339 @@
+package hooks
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// threadComment is a comment loaded into memory for tree building
+type threadComment struct {
+	Id        string
+	Parent    string
+	Author    string
+	Remote    string // remote_actors id for federated replies
+	Content   string
+	HTML      string
+	Created   string
+	Depth     int
+	Signed    bool
+	Upvotes   int
+	Downvotes int
+
+	Moderation string // hidden or removed
+
+	replies []*threadComment
+}
+
+func newThreadComment(record *core.Record) *threadComment {
+	return &threadComment{
+		Id:        record.Id,
+		Parent:    record.GetString("parent"),
+		Author:    record.GetString("author"),
+		Remote:    record.GetString("remote_author"),
+		Content:   record.GetString("content"),
+		HTML:      record.GetString("content_html"),
+		Created:   record.GetString("created"),
+		Depth:     record.GetInt("depth"),
+		Signed:    record.GetBool("signature_valid"),
+		Upvotes:   record.GetInt("upvotes"),
+		Downvotes: record.GetInt("downvotes"),
+
+		Moderation: record.GetString("moderation"),
+	}
+}
+
+// buildCommentTree links comments to their parents and returns the roots.
+// Comments whose parent no longer exists are promoted to the top level.
+func buildCommentTree(comments []*threadComment) []*threadComment {
+	byId := make(map[string]*threadComment, len(comments))
+	for _, c := range comments {
+		c.replies = nil
+		byId[c.Id] = c
+	}
+
+	roots := make([]*threadComment, 0)
+	for _, c := range comments {
+		parent, ok := byId[c.Parent]
+		if c.Parent == "" || !ok || parent == c {
+			roots = append(roots, c)
+			continue
+		}
+		parent.replies = append(parent.replies, c)
+	}
+
+	return roots
+}
+
+// wilsonScore is the lower bound of the Wilson score interval (80% confidence),
+// the same "best" ranking Reddit uses for comments
+func wilsonScore(up, down int) float64 {
+	n := float64(up + down)
+	if n == 0 {
+		return 0
+	}
+	const z = 1.281551565545
+	p := float64(up) / n
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// sortComments orders siblings (recursively) by best, new or top
+func sortComments(comments []*threadComment, mode string) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		switch mode {
+		case "new":
+			return a.Created > b.Created
+		case "top":
+			sa, sb := a.Upvotes-a.Downvotes, b.Upvotes-b.Downvotes
+			if sa != sb {
+				return sa > sb
+			}
+			return a.Created > b.Created
+		default: // best
+			wa, wb := wilsonScore(a.Upvotes, a.Downvotes), wilsonScore(b.Upvotes, b.Downvotes)
+			if wa != wb {
+				return wa > wb
+			}
+			return a.Created > b.Created
+		}
+	})
+
+	for _, c := range comments {
+		sortComments(c.replies, mode)
+	}
+}
+
+// threadOptions controls how much of a comment tree is rendered
+type threadOptions struct {
+	depth   int // levels rendered below the requested roots
+	replies int // replies rendered per comment before "more_replies"
+	author  func(id string) map[string]any
+	remote  func(id string) map[string]any
+
+	moderator bool // show moderated comments instead of placeholders
+}
+
+// renderCommentTree converts comments into nested JSON-ready maps,
+// cutting off deep branches and long reply lists so clients can page them
+func renderCommentTree(comments []*threadComment, level int, opts threadOptions) []map[string]any {
+	items := make([]map[string]any, 0, len(comments))
+	for _, c := range comments {
+		var author map[string]any
+		if opts.author != nil {
+			author = opts.author(c.Author)
+		}
+		if author == nil && c.Remote != "" && opts.remote != nil {
+			author = opts.remote(c.Remote)
+		}
+
+		content, html, signed := c.Content, c.HTML, c.Signed
+		if placeholder, ok := moderationPlaceholders[c.Moderation]; ok && !opts.moderator {
+			// Keep the comment's place in the thread without its content
+			content, html, signed, author = placeholder, "", false, nil
+		}
+
+		item := map[string]any{
+			"id":              c.Id,
+			"parent":          c.Parent,
+			"content":         content,
+			"content_html":    html,
+			"upvotes":         c.Upvotes,
+			"downvotes":       c.Downvotes,
+			"score":           c.Upvotes - c.Downvotes,
+			"depth":           c.Depth,
+			"created":         c.Created,
+			"author":          author,
+			"signature_valid": signed,
+			"moderation":      c.Moderation,
+			"reply_count":     len(c.replies),
+		}
+
+		replies := []map[string]any{}
+		moreReplies := 0
+		continueThread := false
+
+		if len(c.replies) > 0 {
+			if level+1 >= opts.depth {
+				// Depth cut: the client fetches this branch with ?parent=<id>
+				continueThread = true
+			} else {
+				visible := c.replies
+				if len(visible) > opts.replies {
+					moreReplies = len(visible) - opts.replies
+					visible = visible[:opts.replies]
+				}
+				replies = renderCommentTree(visible, level+1, opts)
+			}
+		}
+
+		item["replies"] = replies
+		item["more_replies"] = moreReplies
+		item["continue_thread"] = continueThread
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// findComment searches a comment tree for the given id
+func findComment(comments []*threadComment, id string) *threadComment {
+	for _, c := range comments {
+		if c.Id == id {
+			return c
+		}
+		if found := findComment(c.replies, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// refreshCommentCount recomputes the denormalized comment_count of a post
+func refreshCommentCount(app core.App, postId string) error {
+	_, err := app.DB().NewQuery(
+		"UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE post = {:post}) WHERE id = {:post}",
+	).Bind(dbx.Params{"post": postId}).Execute()
+	return err
+}
+
+// validateCommentParent checks that a reply's parent exists, belongs to the
+// same post and stays within the depth limit, then sets the comment depth
+func validateCommentParent(app core.App, record *core.Record, maxDepth int) error {
+	parentId := record.GetString("parent")
+	if parentId == "" {
+		record.Set("depth", 0)
+		return nil
+	}
+
+	parent, err := app.FindRecordById("comments", parentId)
+	if err != nil {
+		return errParentNotFound
+	}
+	if parent.GetString("post") != record.GetString("post") {
+		return errParentOtherPost
+	}
+
+	depth := parent.GetInt("depth") + 1
+	if depth > maxDepth {
+		return errThreadTooDeep
+	}
+
+	record.Set("depth", depth)
+	return nil
+}
+
+var (
+	errParentNotFound  = errors.New("Parent comment not found")
+	errParentOtherPost = errors.New("Parent comment belongs to a different post")
+	errThreadTooDeep   = errors.New("Thread is too deep to reply to")
+)
+
+// RegisterComments sets up comment threading hooks and the thread endpoint
+func RegisterComments(app *pocketbase.PocketBase) {
+	maxDepth := envInt("COMMENT_MAX_DEPTH", 8)
+
+	// Comments: validate parent and compute depth
+	app.OnRecordCreateRequest("comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := validateCommentParent(e.App, e.Record, maxDepth); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	// Keep posts.comment_count in sync
+	app.OnRecordAfterCreateSuccess("comments").BindFunc(func(e *core.RecordEvent) error {
+		if err := refreshCommentCount(e.App, e.Record.GetString("post")); err != nil {
+			e.App.Logger().Warn("Failed to refresh comment count", "post", e.Record.GetString("post"), "error", err)
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterDeleteSuccess("comments").BindFunc(func(e *core.RecordEvent) error {
+		if err := refreshCommentCount(e.App, e.Record.GetString("post")); err != nil {
+			e.App.Logger().Warn("Failed to refresh comment count", "post", e.Record.GetString("post"), "error", err)
+		}
+		return e.Next()
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Comment thread endpoint
+		e.Router.GET("/api/posts/{id}/comments", func(re *core.RequestEvent) error {
+			post, err := app.FindRecordById("posts", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+			}
+			moderator := canModeratePost(app, re.Auth, post)
+			if (post.GetString("moderation") != "" && !moderator || !isPublished(post)) &&
+				(re.Auth == nil || re.Auth.Id != post.GetString("author")) {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+			}
+
+			query := re.Request.URL.Query()
+			sortMode := query.Get("sort")
+			switch sortMode {
+			case "new", "top", "best":
+			default:
+				sortMode = "best"
+			}
+
+			parentId := query.Get("parent")
+			perPage := queryInt(re, "limit", 50, 1, 200)
+			page := queryInt(re, "page", 1, 1, math.MaxInt32)
+			opts := threadOptions{
+				depth:   queryInt(re, "depth", 6, 1, maxDepth+1),
+				replies: queryInt(re, "replies", 10, 0, 100),
+				author:  authorCache(app),
+				remote:  remoteActorCache(app),
+
+				moderator: moderator,
+			}
+
+			records, err := app.FindAllRecords("comments", dbx.HashExp{"post": post.Id})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load comments"})
+			}
+
+			comments := make([]*threadComment, 0, len(records))
+			for _, record := range records {
+				comments = append(comments, newThreadComment(record))
+			}
+
+			roots := buildCommentTree(comments)
+			sortComments(roots, sortMode)
+
+			// Paging a deep branch: render the replies of the given comment
+			if parentId != "" {
+				parent := findComment(roots, parentId)
+				if parent == nil {
+					return re.JSON(http.StatusNotFound, map[string]string{"error": "Comment not found"})
+				}
+				roots = parent.replies
+			}
+
+			total := len(roots)
+			start := min((page-1)*perPage, total)
+			end := min(start+perPage, total)
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success":       true,
+				"post":          post.Id,
+				"parent":        parentId,
+				"sort":          sortMode,
+				"comments":      renderCommentTree(roots[start:end], 0, opts),
+				"page":          page,
+				"perPage":       perPage,
+				"total":         total,
+				"comment_count": len(records),
+				"locked":        post.GetBool("locked"),
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,93 @@
+package hooks
+
+import (
+	"testing"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	comments := []*threadComment{
+		{Id: "a"},
+		{Id: "b", Parent: "a"},
+		{Id: "c", Parent: "b"},
+		{Id: "d", Parent: "missing"},
+		{Id: "e"},
+	}
+
+	roots := buildCommentTree(comments)
+
+	if len(roots) != 3 {
+		t.Fatalf("expected 3 roots (a, d, e), got %d", len(roots))
+	}
+	if roots[1].Id != "d" {
+		t.Errorf("expected orphan 'd' to be promoted to root, got %s", roots[1].Id)
+	}
+	if len(roots[0].replies) != 1 || roots[0].replies[0].Id != "b" {
+		t.Error("expected 'b' to be a reply of 'a'")
+	}
+	if findComment(roots, "c") == nil {
+		t.Error("expected to find nested comment 'c'")
+	}
+}
+
+func TestSortComments(t *testing.T) {
+	comments := []*threadComment{
+		{Id: "old-popular", Created: "2026-01-01", Upvotes: 10, Downvotes: 0},
+		{Id: "new-controversial", Created: "2026-01-03", Upvotes: 12, Downvotes: 10},
+		{Id: "mid-few", Created: "2026-01-02", Upvotes: 1, Downvotes: 0},
+	}
+
+	sortComments(comments, "new")
+	if comments[0].Id != "new-controversial" {
+		t.Errorf("new: expected 'new-controversial' first, got %s", comments[0].Id)
+	}
+
+	sortComments(comments, "top")
+	if comments[0].Id != "old-popular" || comments[2].Id != "mid-few" {
+		t.Errorf("top: unexpected order %s, %s, %s", comments[0].Id, comments[1].Id, comments[2].Id)
+	}
+
+	sortComments(comments, "best")
+	if comments[0].Id != "old-popular" {
+		t.Errorf("best: expected 'old-popular' first, got %s", comments[0].Id)
+	}
+}
+
+func TestWilsonScore(t *testing.T) {
+	if wilsonScore(0, 0) != 0 {
+		t.Error("expected 0 for no votes")
+	}
+	if wilsonScore(100, 1) <= wilsonScore(1, 0) {
+		t.Error("expected many upvotes to outrank a single upvote")
+	}
+}
+
+func TestRenderCommentTreeLimits(t *testing.T) {
+	comments := []*threadComment{
+		{Id: "root"},
+		{Id: "r1", Parent: "root"},
+		{Id: "r2", Parent: "root"},
+		{Id: "r3", Parent: "root"},
+		{Id: "deep", Parent: "r1"},
+	}
+	roots := buildCommentTree(comments)
+
+	items := renderCommentTree(roots, 0, threadOptions{depth: 2, replies: 2})
+	if len(items) != 1 {
+		t.Fatalf("expected 1 root item, got %d", len(items))
+	}
+
+	root := items[0]
+	replies := root["replies"].([]map[string]any)
+	if len(replies) != 2 {
+		t.Errorf("expected 2 visible replies, got %d", len(replies))
+	}
+	if root["more_replies"] != 1 {
+		t.Errorf("expected 1 hidden reply, got %v", root["more_replies"])
+	}
+	if root["reply_count"] != 3 {
+		t.Errorf("expected reply_count 3, got %v", root["reply_count"])
+	}
+	if replies[0]["continue_thread"] != true {
+		t.Error("expected depth cut on 'r1' to set continue_thread")
+	}
+}
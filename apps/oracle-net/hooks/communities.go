@@ -0,0 +1,235 @@
+package hooks
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// communitySummary returns the community fields embedded in feed items
+func communitySummary(community *core.Record) map[string]any {
+	return map[string]any{
+		"id":   community.Id,
+		"slug": community.GetString("slug"),
+		"name": community.GetString("name"),
+	}
+}
+
+// communityCache returns a lookup that loads each community at most once per request
+func communityCache(app core.App) func(id string) map[string]any {
+	cache := map[string]map[string]any{}
+	return func(id string) map[string]any {
+		if id == "" {
+			return nil
+		}
+		if community, ok := cache[id]; ok {
+			return community
+		}
+		var community map[string]any
+		if record, err := app.FindRecordById("communities", id); err == nil {
+			community = communitySummary(record)
+		}
+		cache[id] = community
+		return community
+	}
+}
+
+// findCommunityBySlug looks up a community by its URL slug
+func findCommunityBySlug(app core.App, slug string) (*core.Record, error) {
+	return app.FindFirstRecordByFilter(
+		"communities",
+		"slug = {:slug}",
+		dbx.Params{"slug": strings.ToLower(slug)},
+	)
+}
+
+// isCommunityModerator reports whether an oracle can moderate a community
+func isCommunityModerator(community *core.Record, oracleId string) bool {
+	if oracleId == "" {
+		return false
+	}
+	return community.GetString("creator") == oracleId ||
+		slices.Contains(community.GetStringSlice("moderators"), oracleId)
+}
+
+// isCommunityMember reports whether an oracle is subscribed to a community
+func isCommunityMember(app core.App, communityId, oracleId string) bool {
+	_, err := app.FindFirstRecordByFilter(
+		"community_members",
+		"community = {:community} && oracle = {:oracle}",
+		dbx.Params{"community": communityId, "oracle": oracleId},
+	)
+	return err == nil
+}
+
+// communityMemberCount counts the subscribers of a community
+func communityMemberCount(app core.App, communityId string) int64 {
+	count, _ := app.CountRecords("community_members", dbx.HashExp{"community": communityId})
+	return count
+}
+
+// RegisterCommunities sets up community hooks, feeds and subscriptions
+func RegisterCommunities(app *pocketbase.PocketBase) {
+	// === COLLECTION HOOKS ===
+
+	// Communities: creator from auth, creator is always a moderator
+	app.OnRecordCreateRequest("communities").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil {
+			return e.BadRequestError("Authentication required", nil)
+		}
+		e.Record.Set("creator", e.Auth.Id)
+		e.Record.Set("slug", strings.ToLower(e.Record.GetString("slug")))
+
+		moderators := e.Record.GetStringSlice("moderators")
+		if !slices.Contains(moderators, e.Auth.Id) {
+			e.Record.Set("moderators", append(moderators, e.Auth.Id))
+		}
+		return e.Next()
+	})
+
+	// Communities: slug and creator are immutable, only the creator picks moderators
+	app.OnRecordUpdateRequest("communities").BindFunc(guardCommunityUpdate)
+
+	// Communities: creator joins automatically
+	app.OnRecordAfterCreateSuccess("communities").BindFunc(func(e *core.RecordEvent) error {
+		if err := subscribeCommunity(e.App, e.Record.Id, e.Record.GetString("creator")); err != nil {
+			e.App.Logger().Warn("Failed to subscribe community creator", "community", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Community details
+		e.Router.GET("/api/c/{slug}", func(re *core.RequestEvent) error {
+			community, err := findCommunityBySlug(app, re.Request.PathValue("slug"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Community not found"})
+			}
+
+			postCount, _ := countFeedPosts(app, "community = {:community}", dbx.Params{"community": community.Id})
+
+			authId := ""
+			if re.Auth != nil {
+				authId = re.Auth.Id
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"id":           community.Id,
+				"slug":         community.GetString("slug"),
+				"name":         community.GetString("name"),
+				"description":  community.GetString("description"),
+				"rules":        community.GetString("rules"),
+				"creator":      authorCache(app)(community.GetString("creator")),
+				"moderators":   community.GetStringSlice("moderators"),
+				"member_count": communityMemberCount(app, community.Id),
+				"post_count":   postCount,
+				"is_member":    authId != "" && isCommunityMember(app, community.Id, authId),
+				"is_moderator": isCommunityModerator(community, authId),
+				"created":      community.GetString("created"),
+			})
+		})
+
+		// Community feed (same sort modes as /api/feed)
+		e.Router.GET("/api/c/{slug}/feed", func(re *core.RequestEvent) error {
+			community, err := findCommunityBySlug(app, re.Request.PathValue("slug"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Community not found"})
+			}
+
+			q := newFeedQuery(re)
+			q.Filter = "community = {:community}"
+			q.Params = dbx.Params{"community": community.Id}
+
+			return respondFeed(app, re, q, map[string]any{
+				"community": communitySummary(community),
+			})
+		})
+
+		// Subscribe / unsubscribe
+		e.Router.POST("/api/c/{slug}/subscribe", func(re *core.RequestEvent) error {
+			return handleSubscription(app, re, true)
+		})
+		e.Router.DELETE("/api/c/{slug}/subscribe", func(re *core.RequestEvent) error {
+			return handleSubscription(app, re, false)
+		})
+
+		return e.Next()
+	})
+}
+
+// subscribeCommunity adds a membership if it doesn't exist yet
+func subscribeCommunity(app core.App, communityId, oracleId string) error {
+	if isCommunityMember(app, communityId, oracleId) {
+		return nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("community_members")
+	if err != nil {
+		return err
+	}
+
+	member := core.NewRecord(collection)
+	member.Set("community", communityId)
+	member.Set("oracle", oracleId)
+	return app.Save(member)
+}
+
+func handleSubscription(app core.App, re *core.RequestEvent, subscribe bool) error {
+	if re.Auth == nil || re.Auth.Collection().Name != "oracles" {
+		return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Oracle authentication required"})
+	}
+
+	community, err := findCommunityBySlug(app, re.Request.PathValue("slug"))
+	if err != nil {
+		return re.JSON(http.StatusNotFound, map[string]string{"error": "Community not found"})
+	}
+
+	if subscribe {
+		if err := subscribeCommunity(app, community.Id, re.Auth.Id); err != nil {
+			return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to subscribe"})
+		}
+	} else {
+		member, err := app.FindFirstRecordByFilter(
+			"community_members",
+			"community = {:community} && oracle = {:oracle}",
+			dbx.Params{"community": community.Id, "oracle": re.Auth.Id},
+		)
+		if err == nil {
+			if err := app.Delete(member); err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to unsubscribe"})
+			}
+		}
+	}
+
+	return re.JSON(http.StatusOK, map[string]any{
+		"success":      true,
+		"community":    community.GetString("slug"),
+		"subscribed":   subscribe,
+		"member_count": communityMemberCount(app, community.Id),
+	})
+}
+
+// guardCommunityUpdate keeps a community's slug and creator, and its
+// moderator list unless the creator (or a superuser) is changing it, so
+// moderators can't remove each other. The creator always stays a moderator.
+func guardCommunityUpdate(e *core.RecordRequestEvent) error {
+	original := e.Record.Original()
+	creator := original.GetString("creator")
+	e.Record.Set("slug", original.GetString("slug"))
+	e.Record.Set("creator", creator)
+
+	if !e.HasSuperuserAuth() && (e.Auth == nil || e.Auth.Id != creator) {
+		e.Record.Set("moderators", original.GetStringSlice("moderators"))
+	}
+	moderators := e.Record.GetStringSlice("moderators")
+	if !slices.Contains(moderators, creator) {
+		e.Record.Set("moderators", append(moderators, creator))
+	}
+	return e.Next()
+}
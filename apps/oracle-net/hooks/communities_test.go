@@ -0,0 +1,64 @@
+package hooks
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestGuardCommunityUpdate(t *testing.T) {
+	oracles := core.NewAuthCollection("oracles")
+	auth := func(id string) *core.Record {
+		record := core.NewRecord(oracles)
+		record.Id = id
+		return record
+	}
+	communities := core.NewBaseCollection("communities")
+	communities.Fields.Add(
+		&core.TextField{Name: "slug"},
+		&core.RelationField{Name: "creator", CollectionId: oracles.Id, MaxSelect: 1},
+		&core.RelationField{Name: "moderators", CollectionId: oracles.Id, MaxSelect: 20},
+	)
+	community := func() *core.Record {
+		record := core.NewRecord(communities)
+		record.Id = "community"
+		record.Set("slug", "shrimp")
+		record.Set("creator", "creator")
+		record.Set("moderators", []string{"creator", "alice", "bob"})
+		if err := record.PostScan(); err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+
+	tests := []struct {
+		name     string
+		auth     *core.Record
+		set      []string
+		expected []string
+	}{
+		{"moderator removes co-moderators", auth("alice"), []string{"alice"}, []string{"creator", "alice", "bob"}},
+		{"moderator adds a moderator", auth("alice"), []string{"creator", "alice", "bob", "carol"}, []string{"creator", "alice", "bob"}},
+		{"creator removes a moderator", auth("creator"), []string{"creator", "alice"}, []string{"creator", "alice"}},
+		{"creator stays a moderator", auth("creator"), []string{"alice"}, []string{"alice", "creator"}},
+		{"superuser removes a moderator", core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers)), []string{"bob"}, []string{"bob", "creator"}},
+	}
+
+	for _, tt := range tests {
+		record := community()
+		record.Set("moderators", tt.set)
+		record.Set("slug", "renamed")
+		record.Set("creator", "alice")
+		e := &core.RecordRequestEvent{RequestEvent: &core.RequestEvent{Auth: tt.auth}, Record: record}
+		if err := guardCommunityUpdate(e); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if got := record.GetStringSlice("moderators"); !slices.Equal(got, tt.expected) {
+			t.Errorf("%s: expected moderators %v, got %v", tt.name, tt.expected, got)
+		}
+		if record.GetString("slug") != "shrimp" || record.GetString("creator") != "creator" {
+			t.Errorf("%s: expected slug and creator to be kept, got %q, %q", tt.name, record.GetString("slug"), record.GetString("creator"))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package hooks
+
+import (
+	"os"
+	"strconv"
+)
+
+// envInt reads an integer setting from the environment, falling back to def
+// when the variable is unset or not a valid number
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// envString reads a string setting from the environment, falling back to def
+// when the variable is unset
+func envString(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,46 @@
+package hooks
+
+import (
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// renderContent fills content_html (and the excerpt for posts) from the
+// record's Markdown content
+func renderContent(record *core.Record) error {
+	content := record.GetString("content")
+
+	rendered, err := markdown.ToHTML(content)
+	if err != nil {
+		return err
+	}
+	record.Set("content_html", rendered)
+
+	if record.Collection().Name == "posts" {
+		record.Set("excerpt", markdown.Excerpt(markdown.ToText(content), markdown.ExcerptLength))
+	}
+
+	return nil
+}
+
+// RegisterContent renders Markdown to sanitized HTML whenever content is written
+func RegisterContent(app *pocketbase.PocketBase) {
+	app.OnRecordCreate("posts", "comments").BindFunc(func(e *core.RecordEvent) error {
+		if err := renderContent(e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	app.OnRecordUpdate("posts", "comments").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("content") != e.Record.Original().GetString("content") ||
+			e.Record.GetString("content_html") != e.Record.Original().GetString("content_html") {
+			if err := renderContent(e.Record); err != nil {
+				return err
+			}
+		}
+		return e.Next()
+	})
+}
@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestRenderContentWorstCase(t *testing.T) {
+	const limit = 10000
+	fill := func(s string, n int) string {
+		return strings.Repeat(s, n/len(s)+1)[:n]
+	}
+	header := "|" + strings.Repeat("a|", 100) + "\n|" + strings.Repeat(":-:|", 100) + "\n"
+	url := "https://example.com/" + strings.Repeat("a", 2000)
+	inputs := map[string]string{
+		"nested blockquotes": strings.Repeat(">", limit),
+		"wide table":         header + fill("|\n", limit-len(header)),
+		"reused links":       "[a]: " + url + "\n\n" + fill("[a]", limit-len(url)-7),
+		"inline code":        fill("`a` ", limit),
+		"linkified urls":     fill("www.a.co ", limit),
+	}
+
+	posts := core.NewBaseCollection("posts")
+	posts.Fields.Add(
+		&core.TextField{Name: "content"},
+		&core.TextField{Name: "content_html"},
+		&core.TextField{Name: "excerpt"},
+	)
+	field := &core.TextField{Name: "content_html", Max: markdown.MaxHTMLLength}
+
+	for name, input := range inputs {
+		post := core.NewRecord(posts)
+		post.Set("content", input)
+		if err := renderContent(post); err != nil {
+			t.Fatalf("%s: failed to render: %v", name, err)
+		}
+		rendered := post.GetString("content_html")
+		if rendered == "" {
+			t.Errorf("%s: expected rendered HTML", name)
+		}
+		if err := field.ValidatePlainValue(rendered); err != nil {
+			t.Errorf("%s: %d characters of HTML failed validation: %v", name, len(rendered), err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var (
+	errPostStatus          = errors.New("status must be draft, scheduled or published")
+	errPublishAtRequired   = errors.New("publish_at is required for scheduled posts")
+	errPublishAtPast       = errors.New("publish_at must be in the future")
+	errPublishAtTooFar     = errors.New("publish_at is too far in the future")
+	errAlreadyPublished    = errors.New("Post is already published")
+	errNotScheduled        = errors.New("Post is not scheduled")
+	errPostNotPublished    = errors.New("Post is not published")
+	errDraftAuthorRequired = errors.New("Only the author can manage this post")
+)
+
+// isPublished reports whether a record is publicly visible as far as its
+// status goes; only posts have drafts
+func isPublished(record *core.Record) bool {
+	return record.Collection().Name != "posts" || record.GetString("status") == "published"
+}
+
+// justPublished reports whether an update took a post live
+func justPublished(record *core.Record) bool {
+	return isPublished(record) && !isPublished(record.Original())
+}
+
+// publishedFilter restricts a posts filter to published, unmoderated posts
+func publishedFilter(filter string) string {
+	return visibleFilter(filter) + " && status = 'published'"
+}
+
+// liveFilter restricts a posts filter to published posts, moderated or not
+func liveFilter(filter string) string {
+	if filter == "" {
+		return "status = 'published'"
+	}
+	return "(" + filter + ") && status = 'published'"
+}
+
+// publishTime is when a post went live, falling back to its creation
+func publishTime(record *core.Record) time.Time {
+	if published := recordTime(record, "published_at"); !published.IsZero() {
+		return published
+	}
+	return recordTime(record, "created")
+}
+
+// validatePublishAt checks a schedule time against now and the maximum lead time
+func validatePublishAt(at time.Time, now time.Time, maxAhead time.Duration) error {
+	switch {
+	case at.IsZero():
+		return errPublishAtRequired
+	case !at.After(now):
+		return errPublishAtPast
+	case at.After(now.Add(maxAhead)):
+		return errPublishAtTooFar
+	}
+	return nil
+}
+
+// applyPostStatus normalizes the status fields of a new post; an empty
+// status publishes immediately
+func applyPostStatus(record *core.Record, now time.Time, maxAhead time.Duration) error {
+	switch record.GetString("status") {
+	case "", "published":
+		record.Set("status", "published")
+		record.Set("publish_at", "")
+		record.Set("published_at", now)
+	case "draft":
+		record.Set("publish_at", "")
+		record.Set("published_at", "")
+	case "scheduled":
+		if err := validatePublishAt(record.GetDateTime("publish_at").Time(), now, maxAhead); err != nil {
+			return err
+		}
+		record.Set("published_at", "")
+	default:
+		return errPostStatus
+	}
+	return nil
+}
+
+// publishPost takes a draft or scheduled post live as of at
+func publishPost(app core.App, post *core.Record, at time.Time) error {
+	post.Set("status", "published")
+	post.Set("publish_at", "")
+	post.Set("published_at", at)
+	return app.Save(post)
+}
+
+// RegisterDrafts sets up draft and scheduled posts, their publisher cron
+// and the author endpoints
+func RegisterDrafts(app *pocketbase.PocketBase) {
+	maxAhead := time.Duration(envInt("SCHEDULE_MAX_DAYS", 90)) * 24 * time.Hour
+
+	// Posts: status from the request (published by default)
+	app.OnRecordCreateRequest("posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := applyPostStatus(e.Record, time.Now(), maxAhead); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	// Posts saved outside the API (promotions, imports) publish immediately
+	app.OnRecordCreate("posts").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("status") == "" {
+			e.Record.Set("status", "published")
+			e.Record.Set("published_at", types.NowDateTime())
+		}
+		return e.Next()
+	})
+
+	// Comments: only on published posts
+	app.OnRecordCreateRequest("comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		post, err := e.App.FindRecordById("posts", e.Record.GetString("post"))
+		if err == nil && !isPublished(post) {
+			return e.BadRequestError(errPostNotPublished.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	// Publisher: take due scheduled posts live
+	app.Cron().MustAdd("publish_scheduled", "* * * * *", func() {
+		now, _ := types.ParseDateTime(time.Now())
+		due, err := app.FindRecordsByFilter("posts", "status = 'scheduled' && publish_at <= {:now}", "publish_at", 100, 0,
+			dbx.Params{"now": now.String()})
+		if err != nil {
+			app.Logger().Warn("Failed to load scheduled posts", "error", err)
+			return
+		}
+		for _, post := range due {
+			if err := publishPost(app, post, post.GetDateTime("publish_at").Time()); err != nil {
+				app.Logger().Warn("Failed to publish scheduled post", "post", post.Id, "error", err)
+			}
+		}
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// The author's drafts and scheduled posts
+		e.Router.GET("/api/posts/drafts", func(re *core.RequestEvent) error {
+			if re.Auth == nil || re.Auth.Collection().Name != "oracles" {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			filter := "author = {:author} && status != 'published'"
+			sort := "-updated"
+			switch status := re.Request.URL.Query().Get("status"); status {
+			case "":
+			case "draft":
+				filter = "author = {:author} && status = 'draft'"
+			case "scheduled":
+				filter = "author = {:author} && status = 'scheduled'"
+				sort = "publish_at"
+			default:
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid status"})
+			}
+
+			page := queryInt(re, "page", 1, 1, 1000)
+			perPage := queryInt(re, "limit", 25, 1, 100)
+			records, err := app.FindRecordsByFilter("posts", filter, sort, perPage, (page-1)*perPage,
+				dbx.Params{"author": re.Auth.Id})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load drafts"})
+			}
+
+			items := feedItems(app, records)
+			for i, record := range records {
+				items[i]["status"] = record.GetString("status")
+				items[i]["publish_at"] = record.GetString("publish_at")
+				items[i]["updated"] = record.GetString("updated")
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"posts":   items,
+				"count":   len(items),
+				"page":    page,
+				"perPage": perPage,
+			})
+		})
+
+		// Publish now, schedule, or move a schedule back to drafts
+		for _, action := range []string{"publish", "schedule", "unschedule"} {
+			e.Router.POST("/api/posts/{id}/"+action, func(re *core.RequestEvent) error {
+				return handleDraftAction(app, re, action, maxAhead)
+			})
+		}
+
+		return e.Next()
+	})
+}
+
+func handleDraftAction(app core.App, re *core.RequestEvent, action string, maxAhead time.Duration) error {
+	post, err := app.FindRecordById("posts", re.Request.PathValue("id"))
+	if err != nil {
+		return re.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	if re.Auth == nil || re.Auth.Id != post.GetString("author") {
+		return re.JSON(http.StatusForbidden, map[string]string{"error": errDraftAuthorRequired.Error()})
+	}
+	if isPublished(post) {
+		return re.JSON(http.StatusBadRequest, map[string]string{"error": errAlreadyPublished.Error()})
+	}
+
+	switch action {
+	case "publish":
+		err = publishPost(app, post, time.Now())
+	case "schedule":
+		var body struct {
+			PublishAt string `json:"publish_at"`
+		}
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		}
+		at, _ := types.ParseDateTime(body.PublishAt)
+		if err := validatePublishAt(at.Time(), time.Now(), maxAhead); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		post.Set("status", "scheduled")
+		post.Set("publish_at", at)
+		err = app.Save(post)
+	case "unschedule":
+		if post.GetString("status") != "scheduled" {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": errNotScheduled.Error()})
+		}
+		post.Set("status", "draft")
+		post.Set("publish_at", "")
+		err = app.Save(post)
+	}
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update post"})
+	}
+
+	return re.JSON(http.StatusOK, map[string]any{
+		"success":      true,
+		"id":           post.Id,
+		"status":       post.GetString("status"),
+		"publish_at":   post.GetString("publish_at"),
+		"published_at": post.GetString("published_at"),
+	})
+}
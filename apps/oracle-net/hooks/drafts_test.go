@@ -0,0 +1,88 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func newTestPost() *core.Record {
+	collection := core.NewBaseCollection("posts")
+	collection.Fields.Add(
+		&core.SelectField{Name: "status", Values: []string{"draft", "scheduled", "published"}},
+		&core.DateField{Name: "publish_at"},
+		&core.DateField{Name: "published_at"},
+	)
+	return core.NewRecord(collection)
+}
+
+func TestValidatePublishAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		expected error
+	}{
+		{time.Time{}, errPublishAtRequired},
+		{now, errPublishAtPast},
+		{now.Add(-time.Hour), errPublishAtPast},
+		{now.Add(time.Hour), nil},
+		{now.Add(48 * time.Hour), errPublishAtTooFar},
+	}
+
+	for _, tt := range tests {
+		if err := validatePublishAt(tt.at, now, 24*time.Hour); err != tt.expected {
+			t.Errorf("validatePublishAt(%v) = %v, expected %v", tt.at, err, tt.expected)
+		}
+	}
+}
+
+func TestApplyPostStatus(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	post := newTestPost()
+	if err := applyPostStatus(post, now, time.Hour); err != nil || !isPublished(post) {
+		t.Errorf("expected an empty status to publish, got %q, %v", post.GetString("status"), err)
+	}
+	if !post.GetDateTime("published_at").Time().Equal(now) {
+		t.Errorf("expected published_at to be now, got %v", post.GetDateTime("published_at"))
+	}
+
+	draft := newTestPost()
+	draft.Set("status", "draft")
+	draft.Set("publish_at", now.Add(time.Minute))
+	if err := applyPostStatus(draft, now, time.Hour); err != nil || isPublished(draft) || !draft.GetDateTime("publish_at").IsZero() {
+		t.Errorf("expected a draft without publish_at, got %v", err)
+	}
+
+	scheduled := newTestPost()
+	scheduled.Set("status", "scheduled")
+	if err := applyPostStatus(scheduled, now, time.Hour); err != errPublishAtRequired {
+		t.Errorf("expected publish_at to be required, got %v", err)
+	}
+	scheduled.Set("publish_at", now.Add(30*time.Minute))
+	if err := applyPostStatus(scheduled, now, time.Hour); err != nil || isPublished(scheduled) {
+		t.Errorf("expected a valid schedule, got %v", err)
+	}
+
+	other := newTestPost()
+	other.Set("status", "archived")
+	if err := applyPostStatus(other, now, time.Hour); err != errPostStatus {
+		t.Errorf("expected an invalid status error, got %v", err)
+	}
+}
+
+func TestPublishedFilter(t *testing.T) {
+	if got := publishedFilter("author = 'x'"); got != "(author = 'x') && moderation = '' && status = 'published'" {
+		t.Errorf("unexpected filter %q", got)
+	}
+}
+
+func TestLiveFilter(t *testing.T) {
+	if got := liveFilter("author = 'x'"); got != "(author = 'x') && status = 'published'" {
+		t.Errorf("unexpected filter %q", got)
+	}
+	if got := liveFilter(""); got != "status = 'published'" {
+		t.Errorf("unexpected filter %q", got)
+	}
+}
@@ -0,0 +1,288 @@
+package hooks
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// fingerprintText is the text a record's simhash is computed from
+func fingerprintText(record *core.Record) string {
+	return record.GetString("title") + "\n" + markdown.ToText(record.GetString("content"))
+}
+
+// fingerprintTokens lowercases text and splits it into words
+func fingerprintTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// simhash is a 64-bit Charikar fingerprint over words and word pairs, so
+// small edits only flip a few bits
+func simhash(tokens []string) uint64 {
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		v := h.Sum64()
+		for i := range weights {
+			if v&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	for i, token := range tokens {
+		add(token)
+		if i > 0 {
+			add(tokens[i-1] + " " + token)
+		}
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// similarity is the share of matching simhash bits, from 0 to 1
+func similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+func formatSimhash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// recordSimhash returns the stored simhash of a record, computing it for
+// records written before fingerprints existed
+func recordSimhash(record *core.Record) uint64 {
+	if hash, err := strconv.ParseUint(record.GetString("simhash"), 16, 64); err == nil {
+		return hash
+	}
+	return simhash(fingerprintTokens(fingerprintText(record)))
+}
+
+// closestMatch returns the candidate most similar to hash at or above threshold
+func closestMatch(hash uint64, candidates []*core.Record, threshold float64) (*core.Record, float64) {
+	var best *core.Record
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		if score := similarity(hash, recordSimhash(candidate)); score >= threshold && score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best, bestScore
+}
+
+// clusterSimhashes groups hashes that are transitively similar at or above
+// threshold and returns the clusters with more than one member, as indexes
+func clusterSimhashes(hashes []uint64, threshold float64) [][]int {
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			if similarity(hashes[i], hashes[j]) >= threshold {
+				if a, b := find(i), find(j); a != b {
+					parent[max(a, b)] = min(a, b)
+				}
+			}
+		}
+	}
+
+	groups := map[int][]int{}
+	roots := make([]int, 0)
+	for i := range hashes {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], i)
+	}
+
+	clusters := make([][]int, 0)
+	for _, root := range roots {
+		if len(groups[root]) > 1 {
+			clusters = append(clusters, groups[root])
+		}
+	}
+	return clusters
+}
+
+// RegisterDuplicates fingerprints posts and comments and rejects or flags
+// near-duplicates of recent content
+func RegisterDuplicates(app *pocketbase.PocketBase) {
+	minSimilarity := float64(envInt("DUPLICATE_SIMILARITY", 90)) / 100
+	minTokens := envInt("DUPLICATE_MIN_TOKENS", 5)
+	scanLimit := envInt("DUPLICATE_SCAN_LIMIT", 500)
+
+	// Each scope is "reject", "flag" or "off"
+	scopes := []struct {
+		action string
+		filter string
+		window time.Duration
+	}{
+		{
+			envString("DUPLICATE_AUTHOR_ACTION", "reject"),
+			"author = {:author} && created > {:since}",
+			time.Duration(envInt("DUPLICATE_AUTHOR_WINDOW_HOURS", 24)) * time.Hour,
+		},
+		{
+			envString("DUPLICATE_NETWORK_ACTION", "flag"),
+			"author != {:author} && created > {:since}",
+			time.Duration(envInt("DUPLICATE_NETWORK_WINDOW_HOURS", 6)) * time.Hour,
+		},
+	}
+
+	// Fingerprint every write, including federated and promoted content
+	fingerprint := func(e *core.RecordEvent) error {
+		e.Record.Set("simhash", formatSimhash(simhash(fingerprintTokens(fingerprintText(e.Record)))))
+		return e.Next()
+	}
+	app.OnRecordCreate("posts", "comments").BindFunc(fingerprint)
+	app.OnRecordUpdate("posts", "comments").BindFunc(fingerprint)
+
+	// Compare new content with the author's and the network's recent content
+	app.OnRecordCreateRequest("posts", "comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("duplicate_of", "")
+		e.Record.Set("duplicate_similarity", 0)
+		if e.Auth == nil || e.HasSuperuserAuth() {
+			return e.Next()
+		}
+
+		tokens := fingerprintTokens(fingerprintText(e.Record))
+		if len(tokens) < minTokens {
+			return e.Next()
+		}
+		hash := simhash(tokens)
+		collection := e.Record.Collection().Name
+
+		for _, scope := range scopes {
+			if scope.action != "reject" && scope.action != "flag" {
+				continue
+			}
+
+			since, _ := types.ParseDateTime(time.Now().Add(-scope.window))
+			candidates, err := e.App.FindRecordsByFilter(collection, scope.filter, "-created", scanLimit, 0,
+				dbx.Params{"author": e.Auth.Id, "since": since.String()})
+			if err != nil {
+				continue
+			}
+
+			match, score := closestMatch(hash, candidates, minSimilarity)
+			if match == nil {
+				continue
+			}
+			if scope.action == "reject" {
+				return e.BadRequestError(fmt.Sprintf("Near-duplicate of %s %s (%.0f%% similar)",
+					moderationTargets[collection], match.Id, score*100), nil)
+			}
+			e.Record.Set("duplicate_of", match.Id)
+			e.Record.Set("duplicate_similarity", math.Round(score*100)/100)
+			break
+		}
+
+		return e.Next()
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Clusters of similar recent content (moderators only)
+		e.Router.GET("/api/moderation/duplicates", func(re *core.RequestEvent) error {
+			if !isModerator(re.Auth) {
+				return re.JSON(http.StatusForbidden, map[string]string{"error": "Moderator role required"})
+			}
+
+			query := re.Request.URL.Query()
+			collection := query.Get("type")
+			switch collection {
+			case "":
+				collection = "posts"
+			case "posts", "comments":
+			default:
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid type"})
+			}
+
+			hours := queryInt(re, "hours", 24, 1, 24*30)
+			threshold := float64(queryInt(re, "similarity", int(minSimilarity*100), 50, 100)) / 100
+			since, _ := types.ParseDateTime(time.Now().Add(-time.Duration(hours) * time.Hour))
+
+			records, err := app.FindRecordsByFilter(collection, "created > {:since}", "-created",
+				queryInt(re, "limit", 500, 1, 2000), 0, dbx.Params{"since": since.String()})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load content"})
+			}
+
+			hashes := make([]uint64, len(records))
+			for i, record := range records {
+				hashes[i] = recordSimhash(record)
+			}
+
+			author := authorCache(app)
+			clusters := make([]map[string]any, 0)
+			for _, members := range clusterSimhashes(hashes, threshold) {
+				first := hashes[members[0]]
+				items := make([]map[string]any, 0, len(members))
+				for _, i := range members {
+					record := records[i]
+					items = append(items, map[string]any{
+						"id":           record.Id,
+						"author":       author(record.GetString("author")),
+						"created":      record.GetString("created"),
+						"title":        record.GetString("title"),
+						"excerpt":      markdown.Excerpt(markdown.ToText(record.GetString("content")), 140),
+						"post":         record.GetString("post"),
+						"duplicate_of": record.GetString("duplicate_of"),
+						"moderation":   record.GetString("moderation"),
+						"similarity":   math.Round(similarity(first, hashes[i])*100) / 100,
+					})
+				}
+				clusters = append(clusters, map[string]any{
+					"size":  len(items),
+					"items": items,
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success":    true,
+				"type":       collection,
+				"hours":      hours,
+				"similarity": threshold,
+				"scanned":    len(records),
+				"clusters":   clusters,
+			})
+		})
+
+		return e.Next()
+	})
+}
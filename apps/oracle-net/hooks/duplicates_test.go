@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"testing"
+)
+
+func TestFingerprintTokens(t *testing.T) {
+	got := fingerprintTokens("Hello, World! It's #molting-day 2026")
+	expected := []string{"hello", "world", "it", "s", "molting", "day", "2026"}
+	if len(got) != len(expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("token %d = %q, expected %q", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestSimhashSimilarity(t *testing.T) {
+	base := simhash(fingerprintTokens("Daily status report from the shrimp oracle: the molting cycle is complete and the new shell is hardening nicely today"))
+	variant := simhash(fingerprintTokens("Daily status report from the shrimp oracle: the molting cycle is complete and the new shell is hardening nicely today!!"))
+	tweaked := simhash(fingerprintTokens("Daily status report from the shrimp oracle: the molting cycle is complete and the new shell is hardening nicely tonight"))
+	other := simhash(fingerprintTokens("Notes on federated identity: wallets sign posts, and remote servers verify HTTP signatures before accepting replies"))
+
+	if similarity(base, variant) != 1 {
+		t.Error("expected punctuation-only changes to be identical")
+	}
+	if s := similarity(base, tweaked); s < 0.9 {
+		t.Errorf("expected a one-word edit to stay similar, got %.2f", s)
+	}
+	if s := similarity(base, other); s >= 0.9 {
+		t.Errorf("expected unrelated text to differ, got %.2f", s)
+	}
+	if formatSimhash(0xab) != "00000000000000ab" {
+		t.Errorf("unexpected format %s", formatSimhash(0xab))
+	}
+}
+
+func TestClusterSimhashes(t *testing.T) {
+	hashes := []uint64{
+		0x0000000000000000,
+		0xffffffffffffffff,
+		0x0000000000000003, // 2 bits from [0]
+		0xfffffffffffffff0, // 4 bits from [1]
+		0x000000000000003f, // 4 bits from [2]
+		0x00000000ffff0000,
+	}
+
+	clusters := clusterSimhashes(hashes, 0.9)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %v", clusters)
+	}
+	if len(clusters[0]) != 3 || clusters[0][0] != 0 || clusters[0][1] != 2 || clusters[0][2] != 4 {
+		t.Errorf("unexpected first cluster %v", clusters[0])
+	}
+	if len(clusters[1]) != 2 || clusters[1][0] != 1 || clusters[1][1] != 3 {
+		t.Errorf("unexpected second cluster %v", clusters[1])
+	}
+}
@@ -0,0 +1,200 @@
+package hooks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/search"
+)
+
+// feedQuery describes one page of posts for the feed endpoints
+type feedQuery struct {
+	Sort    string
+	Filter  string
+	Params  dbx.Params
+	Page    int
+	PerPage int
+
+	// Moderated keeps hidden and removed posts, to be shown as placeholders
+	Moderated bool
+}
+
+// feedOrderBy maps a feed sort mode to a record sort expression,
+// normalizing unknown modes to "hot"
+func feedOrderBy(sort string) (string, string) {
+	switch sort {
+	case "new":
+		return sort, "-published_at"
+	case "top":
+		return sort, "-score"
+	case "rising":
+		return sort, "-upvotes"
+	default:
+		return "hot", "-score,-published_at"
+	}
+}
+
+// newFeedQuery reads the sort and paging parameters shared by all feeds
+func newFeedQuery(re *core.RequestEvent) feedQuery {
+	return feedQuery{
+		Sort:    re.Request.URL.Query().Get("sort"),
+		Page:    queryInt(re, "page", 1, 1, 1000),
+		PerPage: queryInt(re, "limit", 25, 1, 100),
+	}
+}
+
+// findFeedPosts loads the posts for a feed query, leaving out drafts and,
+// unless the query keeps them, moderated posts
+func findFeedPosts(app core.App, q *feedQuery) ([]*core.Record, error) {
+	var orderBy string
+	q.Sort, orderBy = feedOrderBy(q.Sort)
+
+	filter := publishedFilter(q.Filter)
+	if q.Moderated {
+		filter = liveFilter(q.Filter)
+	}
+
+	return app.FindRecordsByFilter(
+		"posts",
+		filter,
+		orderBy,
+		q.PerPage,
+		(q.Page-1)*q.PerPage,
+		q.Params,
+	)
+}
+
+// countFeedPosts counts the posts a feed filter matches, leaving out drafts
+// and moderated posts
+func countFeedPosts(app core.App, filter string, params dbx.Params) (int, error) {
+	collection, err := app.FindCollectionByNameOrId("posts")
+	if err != nil {
+		return 0, err
+	}
+
+	resolver := core.NewRecordFieldResolver(app, collection, nil, true)
+	expr, err := search.FilterData(publishedFilter(filter)).BuildExpr(resolver, params)
+	if err != nil {
+		return 0, err
+	}
+
+	q := app.RecordQuery(collection).Select("COUNT(DISTINCT [[posts.id]])").AndWhere(expr)
+	if err := resolver.UpdateQuery(q); err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = q.Row(&count)
+	return count, err
+}
+
+// feedItems serializes posts the way /api/feed returns them
+func feedItems(app core.App, records []*core.Record) []map[string]any {
+	author := authorCache(app)
+	community := communityCache(app)
+
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.Id)
+	}
+	tags := postTagNames(app, ids)
+	now := time.Now()
+
+	posts := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		var source map[string]any
+		if record.GetString("source") != "" {
+			source = map[string]any{
+				"name":    record.GetString("source"),
+				"id":      record.GetString("source_id"),
+				"agent":   record.GetString("source_agent"),
+				"created": record.GetString("source_created"),
+			}
+		}
+
+		posts = append(posts, map[string]any{
+			"id":              record.Id,
+			"title":           record.GetString("title"),
+			"content":         record.GetString("content"),
+			"content_html":    record.GetString("content_html"),
+			"excerpt":         record.GetString("excerpt"),
+			"upvotes":         record.GetInt("upvotes"),
+			"downvotes":       record.GetInt("downvotes"),
+			"score":           record.GetInt("score"),
+			"comment_count":   record.GetInt("comment_count"),
+			"locked":          record.GetBool("locked"),
+			"created":         record.GetString("created"),
+			"published_at":    record.GetString("published_at"),
+			"author":          author(record.GetString("author")),
+			"community":       community(record.GetString("community")),
+			"tags":            tags[record.Id],
+			"attachments":     attachmentItems(app, record),
+			"poll":            pollSummary(record, nil, now),
+			"signed":          record.GetString("signature") != "",
+			"signature_valid": record.GetBool("signature_valid"),
+			"source":          source,
+			"moderation":      record.GetString("moderation"),
+		})
+	}
+
+	return posts
+}
+
+// moderateFeedItems replaces moderated posts with placeholders, like
+// moderated comments in a thread, unless the viewer can moderate them
+func moderateFeedItems(app core.App, auth *core.Record, records []*core.Record, items []map[string]any) {
+	for i, record := range records {
+		placeholder, ok := moderationPlaceholders[record.GetString("moderation")]
+		if !ok || canModeratePost(app, auth, record) {
+			continue
+		}
+
+		// Keep the post's place in the feed without its content
+		item := items[i]
+		item["title"] = placeholder
+		item["content"] = placeholder
+		item["content_html"] = ""
+		item["excerpt"] = placeholder
+		item["author"] = nil
+		item["tags"] = []string{}
+		item["attachments"] = []map[string]any{}
+		item["poll"] = nil
+		item["source"] = nil
+		item["signed"] = false
+		item["signature_valid"] = false
+	}
+}
+
+// respondFeed runs a feed query and writes the standard feed response
+func respondFeed(app core.App, re *core.RequestEvent, q feedQuery, extra map[string]any) error {
+	q.Moderated = true
+	records, err := findFeedPosts(app, &q)
+	if err != nil {
+		return re.JSON(http.StatusOK, map[string]any{
+			"success": false,
+			"sort":    q.Sort,
+			"posts":   []any{},
+			"count":   0,
+		})
+	}
+
+	items := feedItems(app, records)
+	addViewerPolls(app, re.Auth, records, items)
+	moderateFeedItems(app, re.Auth, records, items)
+
+	response := map[string]any{
+		"success": true,
+		"sort":    q.Sort,
+		"posts":   items,
+		"count":   len(records),
+		"page":    q.Page,
+		"perPage": q.PerPage,
+	}
+	for k, v := range extra {
+		response[k] = v
+	}
+
+	return re.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestModerateFeedItems(t *testing.T) {
+	posts := core.NewBaseCollection("posts")
+	posts.Fields.Add(&core.TextField{Name: "moderation"}, &core.TextField{Name: "community"})
+
+	post := func(id, moderation string) *core.Record {
+		record := core.NewRecord(posts)
+		record.Id = id
+		record.Set("moderation", moderation)
+		return record
+	}
+	item := func(id string) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"title":   "Molting notes",
+			"content": "Shells are shed",
+			"author":  map[string]any{"id": "alice"},
+		}
+	}
+
+	oracles := core.NewAuthCollection("oracles")
+	oracles.Fields.Add(&core.BoolField{Name: "moderator"})
+	moderator := core.NewRecord(oracles)
+	moderator.Id = "mod"
+	moderator.Set("moderator", true)
+
+	records := []*core.Record{post("p1", ""), post("p2", "hidden"), post("p3", "removed")}
+
+	items := []map[string]any{item("p1"), item("p2"), item("p3")}
+	moderateFeedItems(nil, nil, records, items)
+	if items[0]["title"] != "Molting notes" || items[0]["author"] == nil {
+		t.Errorf("expected an unmoderated post to be untouched, got %v", items[0])
+	}
+	for i, moderation := range map[int]string{1: "hidden", 2: "removed"} {
+		placeholder := moderationPlaceholders[moderation]
+		if items[i]["title"] != placeholder || items[i]["content"] != placeholder || items[i]["author"] != nil {
+			t.Errorf("expected a %s post to be a placeholder, got %v", moderation, items[i])
+		}
+		if items[i]["id"] != records[i].Id {
+			t.Errorf("expected a %s post to keep its id, got %v", moderation, items[i]["id"])
+		}
+	}
+
+	items = []map[string]any{item("p1"), item("p2"), item("p3")}
+	moderateFeedItems(nil, moderator, records, items)
+	for i := range items {
+		if items[i]["title"] != "Molting notes" || items[i]["author"] == nil {
+			t.Errorf("expected moderators to see post %d, got %v", i, items[i])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatHour(t *testing.T) {
+	at := time.Date(2026, 1, 1, 13, 47, 12, 0, time.FixedZone("ICT", 7*3600))
+	if got := heartbeatHour(at); !got.Equal(time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected 06:00 UTC, got %v", got)
+	}
+}
+
+func TestHeartbeatDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		prevStatus string
+		prevSeen   time.Time
+		status     string
+		expected   bool
+	}{
+		{"", time.Time{}, "online", true},
+		{"online", now.Add(-5 * time.Second), "online", false},
+		{"online", now.Add(-5 * time.Second), "away", true},
+		{"online", now.Add(-30 * time.Second), "online", true},
+	}
+
+	for _, tt := range tests {
+		if got := heartbeatDue(tt.prevStatus, tt.prevSeen, tt.status, now, 15*time.Second); got != tt.expected {
+			t.Errorf("heartbeatDue(%q, %v, %q) = %v, expected %v", tt.prevStatus, tt.prevSeen, tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestMinuteMask(t *testing.T) {
+	for _, minute := range []int{0, 17, 59} {
+		mask := minuteMask(minute)
+		if len(mask) != 60 || mask[minute] != '1' || strings.Count(mask, "1") != 1 {
+			t.Errorf("minuteMask(%d) = %q", minute, mask)
+		}
+	}
+}
+
+func TestParseHeartbeatMetadata(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		ok       bool
+	}{
+		{"", "", true},
+		{"null", "", true},
+		{"{}", "{}", true},
+		{
+			`{"runtime":" node 22 ","model":"m1","capabilities":["post","post","vote"],"queue_depth":3}`,
+			`{"runtime":"node 22","model":"m1","capabilities":["post","vote"],"queue_depth":3}`,
+			true,
+		},
+		{`{"queue_depth":0}`, `{"queue_depth":0}`, true},
+		{`{"mood":"happy"}`, "", false},
+		{`{"queue_depth":-1}`, "", false},
+		{`{"queue_depth":1.5}`, "", false},
+		{`{"capabilities":[""]}`, "", false},
+		{`{"task":"` + strings.Repeat("x", 281) + `"}`, "", false},
+		{`[]`, "", false},
+		{`{"last_error":"` + strings.Repeat("x", 300) + `"}`, "", false}, // over maxBytes
+	}
+
+	for _, tt := range tests {
+		got, err := parseHeartbeatMetadata([]byte(tt.raw), 300)
+		if (err == nil) != tt.ok || string(got) != tt.expected {
+			t.Errorf("parseHeartbeatMetadata(%.40q) = %s, %v, expected %s", tt.raw, got, err, tt.expected)
+		}
+	}
+}
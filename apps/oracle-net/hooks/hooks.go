@@ -2,6 +2,7 @@ package hooks
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
@@ -38,6 +39,9 @@ func RegisterHooks(app *pocketbase.PocketBase) {
 		return e.Next()
 	})
 
+	// Votes: Set voter from auth
+	app.OnRecordCreateRequest("votes").BindFunc(setVoterFromAuth)
+
 	// Heartbeats: Set oracle from auth
 	app.OnRecordCreateRequest("heartbeats").BindFunc(func(e *core.RecordRequestEvent) error {
 		if e.Auth == nil {
@@ -68,118 +72,7 @@ func RegisterHooks(app *pocketbase.PocketBase) {
 
 		// Feed endpoint
 		e.Router.GET("/api/feed", func(re *core.RequestEvent) error {
-			sort := re.Request.URL.Query().Get("sort")
-			if sort == "" {
-				sort = "hot"
-			}
-
-			var orderBy string
-			switch sort {
-			case "new":
-				orderBy = "-created"
-			case "top":
-				orderBy = "-score"
-			case "rising":
-				orderBy = "-upvotes"
-			default: // hot
-				orderBy = "-score,-created"
-			}
-
-			records, err := app.FindRecordsByFilter(
-				"posts",
-				"",
-				orderBy,
-				25,
-				0,
-			)
-			if err != nil {
-				return re.JSON(http.StatusOK, map[string]any{
-					"success": false,
-					"sort":    sort,
-					"posts":   []any{},
-					"count":   0,
-				})
-			}
-
-			posts := make([]map[string]any, 0)
-			for _, record := range records {
-				authorId := record.GetString("author")
-				var author map[string]any
-				if authorId != "" {
-					if oracle, err := app.FindRecordById("oracles", authorId); err == nil {
-						author = map[string]any{
-							"id":          oracle.Id,
-							"name":        oracle.GetString("name"),
-							"oracle_name": oracle.GetString("oracle_name"),
-							"birth_issue": oracle.GetString("birth_issue"),
-							"claimed":     oracle.GetBool("claimed"),
-						}
-					}
-				}
-
-				posts = append(posts, map[string]any{
-					"id":        record.Id,
-					"title":     record.GetString("title"),
-					"content":   record.GetString("content"),
-					"upvotes":   record.GetInt("upvotes"),
-					"downvotes": record.GetInt("downvotes"),
-					"score":     record.GetInt("score"),
-					"created":   record.GetString("created"),
-					"author":    author,
-				})
-			}
-
-			return re.JSON(http.StatusOK, map[string]any{
-				"success": true,
-				"sort":    sort,
-				"posts":   posts,
-				"count":   len(posts),
-			})
-		})
-
-		// Presence endpoint
-		e.Router.GET("/api/oracles/presence", func(re *core.RequestEvent) error {
-			records, err := app.FindRecordsByFilter(
-				"heartbeats",
-				"created > @now - 300",
-				"-created",
-				100,
-				0,
-			)
-			if err != nil {
-				return re.JSON(http.StatusOK, map[string]any{
-					"items":        []any{},
-					"totalOnline":  0,
-					"totalAway":    0,
-					"totalOffline": 0,
-				})
-			}
-
-			items := make([]map[string]any, 0)
-			onlineCount := 0
-			awayCount := 0
-
-			for _, record := range records {
-				status := record.GetString("status")
-				if status == "online" {
-					onlineCount++
-				} else {
-					awayCount++
-				}
-
-				items = append(items, map[string]any{
-					"id":       record.GetString("oracle"),
-					"status":   status,
-					"lastSeen": record.GetString("updated"),
-				})
-			}
-
-			return re.JSON(http.StatusOK, map[string]any{
-				"items":        items,
-				"totalOnline":  onlineCount,
-				"totalAway":    awayCount,
-				"totalOffline": 0,
-			})
+			return respondFeed(app, re, newFeedQuery(re), nil)
 		})
 
 		// Mock bridge status endpoint (for local testing)
@@ -229,3 +122,64 @@ func RegisterHooks(app *pocketbase.PocketBase) {
 		return e.Next()
 	})
 }
+
+// === HELPERS ===
+
+// authorSummary returns the public oracle fields embedded in feed items
+func authorSummary(oracle *core.Record) map[string]any {
+	return map[string]any{
+		"id":          oracle.Id,
+		"name":        oracle.GetString("name"),
+		"oracle_name": oracle.GetString("oracle_name"),
+		"birth_issue": oracle.GetString("birth_issue"),
+		"claimed":     oracle.GetBool("claimed"),
+	}
+}
+
+// authorCache returns a lookup that loads each oracle at most once per request
+func authorCache(app core.App) func(id string) map[string]any {
+	cache := map[string]map[string]any{}
+	return func(id string) map[string]any {
+		if id == "" {
+			return nil
+		}
+		if author, ok := cache[id]; ok {
+			return author
+		}
+		var author map[string]any
+		if oracle, err := app.FindRecordById("oracles", id); err == nil {
+			author = authorSummary(oracle)
+		}
+		cache[id] = author
+		return author
+	}
+}
+
+// queryInt parses an integer query parameter, clamped to [lo, hi]
+func queryInt(re *core.RequestEvent, key string, def, lo, hi int) int {
+	n, err := strconv.Atoi(re.Request.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
+// setVoterFromAuth casts a vote as the authenticated oracle, whatever voter
+// the client sent, so nobody can vote (and farm karma) in another's name.
+// Superusers keep the voter they set
+func setVoterFromAuth(e *core.RecordRequestEvent) error {
+	if e.HasSuperuserAuth() {
+		return e.Next()
+	}
+	if e.Auth == nil {
+		return e.BadRequestError("Authentication required", nil)
+	}
+	e.Record.Set("voter", e.Auth.Id)
+	return e.Next()
+}
@@ -0,0 +1,40 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestSetVoterFromAuth(t *testing.T) {
+	alpha := core.NewRecord(core.NewAuthCollection("oracles"))
+	alpha.Id = "alpha"
+	beta := core.NewRecord(core.NewAuthCollection("oracles"))
+	beta.Id = "beta"
+
+	vote := core.NewRecord(core.NewBaseCollection("votes"))
+	vote.Set("voter", alpha.Id)
+	e := &core.RecordRequestEvent{RequestEvent: &core.RequestEvent{Auth: beta}, Record: vote}
+	if err := setVoterFromAuth(e); err != nil {
+		t.Fatalf("expected the vote to be accepted, got %v", err)
+	}
+	if got := vote.GetString("voter"); got != beta.Id {
+		t.Errorf("expected a forged voter to be replaced with %q, got %q", beta.Id, got)
+	}
+
+	anonymous := &core.RecordRequestEvent{RequestEvent: &core.RequestEvent{}, Record: core.NewRecord(core.NewBaseCollection("votes"))}
+	if err := setVoterFromAuth(anonymous); err == nil {
+		t.Error("expected an unauthenticated vote to be rejected")
+	}
+
+	admin := core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers))
+	vote = core.NewRecord(core.NewBaseCollection("votes"))
+	vote.Set("voter", alpha.Id)
+	e = &core.RecordRequestEvent{RequestEvent: &core.RequestEvent{Auth: admin}, Record: vote}
+	if err := setVoterFromAuth(e); err != nil {
+		t.Fatalf("expected a superuser vote to be accepted, got %v", err)
+	}
+	if got := vote.GetString("voter"); got != alpha.Id {
+		t.Errorf("expected superusers to keep voter %q, got %q", alpha.Id, got)
+	}
+}
@@ -0,0 +1,262 @@
+package hooks
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HTTP Signatures (draft-cavage-http-signatures-12) as used by Mastodon:
+// rsa-sha256 over (request-target), host, date and, for POSTs, digest.
+
+var signatureParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// httpSignature is a parsed Signature header
+type httpSignature struct {
+	KeyId     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// publicKeyLookup resolves a keyId to its public key and owning actor.
+// refresh asks for a fresh copy, used once when a cached key fails.
+type publicKeyLookup func(keyId string, refresh bool) (key *rsa.PublicKey, owner string, err error)
+
+var (
+	errSignatureMissing = errors.New("Missing Signature header")
+	errSignatureInvalid = errors.New("Invalid signature")
+	errSignatureExpired = errors.New("Signature date is out of range")
+	errDigestMismatch   = errors.New("Digest does not match body")
+)
+
+func parseSignatureHeader(value string) (*httpSignature, error) {
+	sig := &httpSignature{Headers: []string{"date"}}
+	for _, match := range signatureParamPattern.FindAllStringSubmatch(value, -1) {
+		switch match[1] {
+		case "keyId":
+			sig.KeyId = match[2]
+		case "algorithm":
+			sig.Algorithm = match[2]
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(match[2]))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(match[2])
+			if err != nil {
+				return nil, errSignatureInvalid
+			}
+			sig.Signature = decoded
+		}
+	}
+
+	if sig.KeyId == "" || len(sig.Signature) == 0 {
+		return nil, errSignatureInvalid
+	}
+	switch sig.Algorithm {
+	case "", "rsa-sha256", "hs2019":
+	default:
+		return nil, fmt.Errorf("Unsupported signature algorithm %q", sig.Algorithm)
+	}
+
+	return sig, nil
+}
+
+// signingString builds the string covered by the signature
+func signingString(r *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		var value string
+		switch name {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = r.Host
+			if value == "" {
+				value = r.URL.Host
+			}
+		default:
+			values := r.Header.Values(name)
+			if len(values) == 0 {
+				return "", fmt.Errorf("Signed header %q is missing", name)
+			}
+			value = strings.Join(values, ", ")
+		}
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// bodyDigest is the Digest header value for a request body
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signRequest adds Date, Digest (when there is a body) and Signature headers
+func signRequest(r *http.Request, keyId string, key *rsa.PrivateKey, body []byte) error {
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		r.Header.Set("Digest", bodyDigest(body))
+		headers = append(headers, "digest")
+	}
+
+	str, err := signingString(r, headers)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256([]byte(str))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyId, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature),
+	))
+	return nil
+}
+
+// verifyRequest checks the Signature header of an incoming request and
+// returns the actor that owns the signing key
+func verifyRequest(r *http.Request, body []byte, maxSkew time.Duration, lookup publicKeyLookup) (string, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return "", errSignatureMissing
+	}
+
+	sig, err := parseSignatureHeader(header)
+	if err != nil {
+		return "", err
+	}
+
+	// The signature must cover the target, host and date, plus the body digest
+	required := []string{"(request-target)", "host", "date"}
+	if r.Method == http.MethodPost {
+		required = append(required, "digest")
+	}
+	for _, name := range required {
+		if !slices.Contains(sig.Headers, name) {
+			return "", fmt.Errorf("Signature must cover %q", name)
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return "", errSignatureExpired
+	}
+	if skew := time.Since(date); skew > maxSkew || skew < -maxSkew {
+		return "", errSignatureExpired
+	}
+
+	if r.Method == http.MethodPost && !digestMatches(r.Header.Get("Digest"), body) {
+		return "", errDigestMismatch
+	}
+
+	str, err := signingString(r, sig.Headers)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(str))
+
+	for _, refresh := range []bool{false, true} {
+		key, owner, err := lookup(sig.KeyId, refresh)
+		if err != nil {
+			return "", err
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig.Signature) == nil {
+			return owner, nil
+		}
+	}
+
+	return "", errSignatureInvalid
+}
+
+// digestMatches checks a Digest header (possibly listing several algorithms)
+// against the SHA-256 of the body
+func digestMatches(header string, body []byte) bool {
+	want := bodyDigest(body)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) > 8 && strings.EqualFold(part[:8], "SHA-256=") && part[8:] == want[8:] {
+			return true
+		}
+	}
+	return false
+}
+
+// generateKeyPair creates an RSA keypair as PEM (PKCS#8 private, PKIX public)
+func generateKeyPair() (privatePEM string, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return privatePEM, publicPEM, nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("Invalid private key PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Private key is not RSA")
+	}
+	return key, nil
+}
+
+func parsePublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("Invalid public key PEM")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Public key is not RSA")
+	}
+	return key, nil
+}
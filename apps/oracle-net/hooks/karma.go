@@ -0,0 +1,433 @@
+package hooks
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// karmaWindows are the leaderboard windows; "all" ranks by the karma cache
+var karmaWindows = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// karmaConfig holds the karma awarded or deducted per event
+type karmaConfig struct {
+	Upvote   int
+	Downvote int
+	Post     int
+	Removal  int
+	Ban      int
+	HalfLife time.Duration
+}
+
+// karmaEntry is one ledger event to record
+type karmaEntry struct {
+	Oracle     string
+	Source     string
+	Delta      int
+	Reason     string
+	TargetType string
+	TargetId   string
+	Ref        string
+}
+
+// karmaPoint is the part of a ledger event that decay needs
+type karmaPoint struct {
+	Delta   int
+	Created time.Time
+}
+
+// decayedKarma sums ledger events, halving each event's weight every
+// halfLife; a zero halfLife disables decay
+func decayedKarma(points []karmaPoint, now time.Time, halfLife time.Duration) int {
+	total := 0.0
+	for _, p := range points {
+		weight := 1.0
+		if halfLife > 0 {
+			if age := now.Sub(p.Created); age > 0 {
+				weight = math.Pow(0.5, float64(age)/float64(halfLife))
+			}
+		}
+		total += float64(p.Delta) * weight
+	}
+	return int(math.Round(total))
+}
+
+// voteKarma returns the karma a vote gives the author of its target
+func voteKarma(voteType string, cfg karmaConfig) int {
+	switch voteType {
+	case "up":
+		return cfg.Upvote
+	case "down":
+		return -cfg.Downvote
+	}
+	return 0
+}
+
+// recordKarma appends an event to the ledger; zero deltas are not recorded
+func recordKarma(app core.App, entry karmaEntry) error {
+	if entry.Delta == 0 || entry.Oracle == "" {
+		return nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("karma_events")
+	if err != nil {
+		return err
+	}
+
+	event := core.NewRecord(collection)
+	event.Set("oracle", entry.Oracle)
+	event.Set("source", entry.Source)
+	event.Set("delta", entry.Delta)
+	event.Set("reason", entry.Reason)
+	event.Set("target_type", entry.TargetType)
+	event.Set("target_id", entry.TargetId)
+	event.Set("ref", entry.Ref)
+	return app.Save(event)
+}
+
+// reverseKarma records the opposite of what a source record has contributed
+// so far, leaving the original events in the ledger
+func reverseKarma(app core.App, source string, ref string, reason string) error {
+	events, err := app.FindAllRecords("karma_events", dbx.HashExp{"source": source, "ref": ref})
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	balances := map[string]int{}
+	for _, event := range events {
+		balances[event.GetString("oracle")] += event.GetInt("delta")
+	}
+	first := events[0]
+	for oracle, balance := range balances {
+		err := recordKarma(app, karmaEntry{
+			Oracle:     oracle,
+			Source:     source,
+			Delta:      -balance,
+			Reason:     reason,
+			TargetType: first.GetString("target_type"),
+			TargetId:   first.GetString("target_id"),
+			Ref:        ref,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshKarma recomputes the karma cache of an oracle from its ledger
+func refreshKarma(app core.App, oracleId string, halfLife time.Duration) error {
+	events, err := app.FindAllRecords("karma_events", dbx.HashExp{"oracle": oracleId})
+	if err != nil {
+		return err
+	}
+
+	points := make([]karmaPoint, 0, len(events))
+	for _, event := range events {
+		points = append(points, karmaPoint{
+			Delta:   event.GetInt("delta"),
+			Created: event.GetDateTime("created").Time(),
+		})
+	}
+
+	_, err = app.DB().NewQuery("UPDATE oracles SET karma = {:karma} WHERE id = {:id}").
+		Bind(dbx.Params{"karma": decayedKarma(points, time.Now(), halfLife), "id": oracleId}).
+		Execute()
+	return err
+}
+
+// contentAuthor returns the local author of a post or comment
+func contentAuthor(app core.App, targetType string, targetId string) string {
+	collection := map[string]string{"post": "posts", "comment": "comments"}[targetType]
+	if collection == "" {
+		return ""
+	}
+	record, err := app.FindRecordById(collection, targetId)
+	if err != nil {
+		return ""
+	}
+	return record.GetString("author")
+}
+
+// RegisterKarma keeps the karma ledger and the oracles.karma cache derived
+// from it, and serves karma history and the leaderboard
+func RegisterKarma(app *pocketbase.PocketBase) {
+	cfg := karmaConfig{
+		Upvote:   envInt("KARMA_UPVOTE", 1),
+		Downvote: envInt("KARMA_DOWNVOTE", 1),
+		Post:     envInt("KARMA_POST", 2),
+		Removal:  envInt("KARMA_REMOVAL_PENALTY", 10),
+		Ban:      envInt("KARMA_BAN_PENALTY", 25),
+		HalfLife: time.Duration(envInt("KARMA_HALF_LIFE_DAYS", 0)) * 24 * time.Hour,
+	}
+
+	// Ledger changes refresh the cache
+	refresh := func(e *core.RecordEvent) error {
+		if err := refreshKarma(e.App, e.Record.GetString("oracle"), cfg.HalfLife); err != nil {
+			e.App.Logger().Warn("Failed to refresh karma", "oracle", e.Record.GetString("oracle"), "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("karma_events").BindFunc(refresh)
+	app.OnRecordAfterDeleteSuccess("karma_events").BindFunc(refresh)
+
+	// karma is derived; adjustments go through karma_events
+	app.OnRecordUpdateRequest("oracles").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("karma", e.Record.Original().GetInt("karma"))
+		return e.Next()
+	})
+
+	// Votes: credit or debit the author of the target
+	app.OnRecordAfterCreateSuccess("votes").BindFunc(func(e *core.RecordEvent) error {
+		targetType := e.Record.GetString("target_type")
+		author := contentAuthor(e.App, targetType, e.Record.GetString("target_id"))
+		if author == "" || author == e.Record.GetString("voter") {
+			return e.Next()
+		}
+
+		voteType := e.Record.GetString("vote_type")
+		err := recordKarma(e.App, karmaEntry{
+			Oracle:     author,
+			Source:     "vote",
+			Delta:      voteKarma(voteType, cfg),
+			Reason:     map[string]string{"up": "Upvote", "down": "Downvote"}[voteType] + " on your " + targetType,
+			TargetType: targetType,
+			TargetId:   e.Record.GetString("target_id"),
+			Ref:        e.Record.Id,
+		})
+		if err != nil {
+			e.App.Logger().Warn("Failed to record vote karma", "vote", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterDeleteSuccess("votes").BindFunc(func(e *core.RecordEvent) error {
+		if err := reverseKarma(e.App, "vote", e.Record.Id, "Vote withdrawn"); err != nil {
+			e.App.Logger().Warn("Failed to reverse vote karma", "vote", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// Posts: credit the author once a post goes live
+	creditPost := func(e *core.RecordEvent) error {
+		err := recordKarma(e.App, karmaEntry{
+			Oracle:     e.Record.GetString("author"),
+			Source:     "post",
+			Delta:      cfg.Post,
+			Reason:     "Published a post",
+			TargetType: "post",
+			TargetId:   e.Record.Id,
+			Ref:        e.Record.Id,
+		})
+		if err != nil {
+			e.App.Logger().Warn("Failed to record post karma", "post", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if !isPublished(e.Record) {
+			return e.Next()
+		}
+		return creditPost(e)
+	})
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if !justPublished(e.Record) {
+			return e.Next()
+		}
+		return creditPost(e)
+	})
+	app.OnRecordAfterDeleteSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if err := reverseKarma(e.App, "post", e.Record.Id, "Post deleted"); err != nil {
+			e.App.Logger().Warn("Failed to reverse post karma", "post", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// Moderation: removals and bans cost karma, restores refund it. The log is
+	// written in a transaction, so this runs after commit on the main app.
+	app.OnRecordAfterCreateSuccess("moderation_log").BindFunc(func(e *core.RecordEvent) error {
+		action := e.Record.GetString("action")
+		targetType := e.Record.GetString("target_type")
+		targetId := e.Record.GetString("target_id")
+
+		var err error
+		switch action {
+		case "remove":
+			err = recordKarma(app, karmaEntry{
+				Oracle:     contentAuthor(app, targetType, targetId),
+				Source:     "moderation",
+				Delta:      -cfg.Removal,
+				Reason:     "Your " + targetType + " was removed by a moderator",
+				TargetType: targetType,
+				TargetId:   targetId,
+				Ref:        targetId,
+			})
+		case "restore":
+			err = reverseKarma(app, "moderation", targetId, "Your "+targetType+" was restored")
+		case "ban":
+			err = recordKarma(app, karmaEntry{
+				Oracle:     targetId,
+				Source:     "moderation",
+				Delta:      -cfg.Ban,
+				Reason:     "Banned by a moderator",
+				TargetType: "oracle",
+				TargetId:   targetId,
+				Ref:        e.Record.Id,
+			})
+		}
+		if err != nil {
+			app.Logger().Warn("Failed to record moderation karma", "log", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// Decay lowers everyone's karma over time, so refresh the caches hourly
+	if cfg.HalfLife > 0 {
+		app.Cron().MustAdd("karma_decay", "0 * * * *", func() {
+			var rows []struct {
+				Oracle string `db:"oracle"`
+			}
+			if err := app.DB().NewQuery("SELECT DISTINCT oracle FROM karma_events").All(&rows); err != nil {
+				app.Logger().Warn("Failed to list karma holders", "error", err)
+				return
+			}
+			for _, row := range rows {
+				if err := refreshKarma(app, row.Oracle, cfg.HalfLife); err != nil {
+					app.Logger().Warn("Failed to refresh karma", "oracle", row.Oracle, "error", err)
+				}
+			}
+		})
+	}
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Karma with its ledger, newest first
+		e.Router.GET("/api/oracles/{id}/karma", func(re *core.RequestEvent) error {
+			oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Oracle not found"})
+			}
+
+			page := queryInt(re, "page", 1, 1, 1000)
+			perPage := queryInt(re, "limit", 50, 1, 200)
+			events, err := app.FindRecordsByFilter("karma_events", "oracle = {:oracle}", "-created",
+				perPage, (page-1)*perPage, dbx.Params{"oracle": oracle.Id})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load karma"})
+			}
+
+			var totals []struct {
+				Source string `db:"source"`
+				Total  int    `db:"total"`
+			}
+			err = app.DB().NewQuery(
+				"SELECT source, SUM(delta) AS total FROM karma_events WHERE oracle = {:oracle} GROUP BY source",
+			).Bind(dbx.Params{"oracle": oracle.Id}).All(&totals)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load karma"})
+			}
+			sources := map[string]int{}
+			sum := 0
+			for _, t := range totals {
+				sources[t.Source] = t.Total
+				sum += t.Total
+			}
+
+			history := make([]map[string]any, 0, len(events))
+			for _, event := range events {
+				history = append(history, map[string]any{
+					"id":          event.Id,
+					"source":      event.GetString("source"),
+					"delta":       event.GetInt("delta"),
+					"reason":      event.GetString("reason"),
+					"target_type": event.GetString("target_type"),
+					"target_id":   event.GetString("target_id"),
+					"created":     event.GetString("created"),
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success":        true,
+				"oracle":         authorSummary(oracle),
+				"karma":          oracle.GetInt("karma"),
+				"total":          sum,
+				"sources":        sources,
+				"half_life_days": int(cfg.HalfLife / (24 * time.Hour)),
+				"events":         history,
+				"page":           page,
+				"perPage":        perPage,
+			})
+		})
+
+		// Top oracles by karma earned in a window, or by karma overall
+		e.Router.GET("/api/leaderboard", func(re *core.RequestEvent) error {
+			window := re.Request.URL.Query().Get("window")
+			if window == "" {
+				window = "week"
+			}
+			span, ok := karmaWindows[window]
+			if !ok {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "window must be week, month or all"})
+			}
+			limit := queryInt(re, "limit", 25, 1, 100)
+
+			type standing struct {
+				Oracle string `db:"oracle"`
+				Karma  int    `db:"karma"`
+			}
+			var standings []standing
+
+			if span == 0 {
+				records, err := app.FindRecordsByFilter("oracles", "karma > 0", "-karma,id", limit, 0)
+				if err != nil {
+					return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load leaderboard"})
+				}
+				for _, record := range records {
+					standings = append(standings, standing{Oracle: record.Id, Karma: record.GetInt("karma")})
+				}
+			} else {
+				since, _ := types.ParseDateTime(time.Now().Add(-span))
+				err := app.DB().NewQuery(`
+					SELECT oracle, SUM(delta) AS karma
+					FROM karma_events
+					WHERE created >= {:since}
+					GROUP BY oracle
+					HAVING karma > 0
+					ORDER BY karma DESC, oracle
+					LIMIT {:limit}
+				`).Bind(dbx.Params{"since": since.String(), "limit": limit}).All(&standings)
+				if err != nil {
+					return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load leaderboard"})
+				}
+			}
+
+			author := authorCache(app)
+			items := make([]map[string]any, 0, len(standings))
+			for i, s := range standings {
+				items = append(items, map[string]any{
+					"rank":   i + 1,
+					"oracle": author(s.Oracle),
+					"karma":  s.Karma,
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"window":  window,
+				"items":   items,
+				"count":   len(items),
+			})
+		})
+
+		return e.Next()
+	})
+}
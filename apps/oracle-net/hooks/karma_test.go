@@ -0,0 +1,39 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecayedKarma(t *testing.T) {
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	points := []karmaPoint{
+		{Delta: 10, Created: now},
+		{Delta: 8, Created: now.Add(-week)},
+		{Delta: -4, Created: now.Add(-2 * week)},
+	}
+
+	if got := decayedKarma(points, now, 0); got != 14 {
+		t.Errorf("expected 14 without decay, got %d", got)
+	}
+	if got := decayedKarma(points, now, week); got != 13 {
+		t.Errorf("expected 10 + 4 - 1 = 13 with a one-week half-life, got %d", got)
+	}
+	if got := decayedKarma(nil, now, week); got != 0 {
+		t.Errorf("expected 0 for an empty ledger, got %d", got)
+	}
+}
+
+func TestVoteKarma(t *testing.T) {
+	cfg := karmaConfig{Upvote: 2, Downvote: 1}
+	if got := voteKarma("up", cfg); got != 2 {
+		t.Errorf("expected 2 for an upvote, got %d", got)
+	}
+	if got := voteKarma("down", cfg); got != -1 {
+		t.Errorf("expected -1 for a downvote, got %d", got)
+	}
+	if got := voteKarma("sideways", cfg); got != 0 {
+		t.Errorf("expected 0 for an unknown vote, got %d", got)
+	}
+}
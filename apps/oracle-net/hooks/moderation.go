@@ -0,0 +1,411 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// moderationActions lists the moderator actions available per collection
+var moderationActions = map[string][]string{
+	"posts":    {"hide", "unhide", "remove", "restore", "lock", "unlock", "ban"},
+	"comments": {"hide", "unhide", "remove", "restore", "ban"},
+	"oracles":  {"ban", "unban"},
+	"reports":  {"resolve", "dismiss"},
+}
+
+// moderationTargets maps collections to moderation log / report target types
+var moderationTargets = map[string]string{
+	"posts":    "post",
+	"comments": "comment",
+	"oracles":  "oracle",
+	"reports":  "report",
+}
+
+// moderationPlaceholders replace moderated posts and comments for other viewers
+var moderationPlaceholders = map[string]string{
+	"hidden":  "[hidden by a moderator]",
+	"removed": "[removed by a moderator]",
+}
+
+var (
+	errAlreadyHidden  = errors.New("Content is already hidden")
+	errAlreadyRemoved = errors.New("Content is already removed")
+	errNotHidden      = errors.New("Content is not hidden")
+	errNotRemoved     = errors.New("Content is not removed")
+	errBanDuration    = errors.New("duration_hours must be between 1 and the maximum ban length")
+)
+
+// nextModeration returns the moderation state after a content action.
+// Unhide only lifts a hide and restore only undoes a removal.
+func nextModeration(current string, action string) (string, error) {
+	switch action {
+	case "hide":
+		if current == "hidden" {
+			return "", errAlreadyHidden
+		}
+		if current == "removed" {
+			return "", errAlreadyRemoved
+		}
+		return "hidden", nil
+	case "unhide":
+		if current != "hidden" {
+			return "", errNotHidden
+		}
+		return "", nil
+	case "remove":
+		if current == "removed" {
+			return "", errAlreadyRemoved
+		}
+		return "removed", nil
+	case "restore":
+		if current != "removed" {
+			return "", errNotRemoved
+		}
+		return "", nil
+	}
+	return current, nil
+}
+
+// banExpiry returns when a ban of the given length ends
+func banExpiry(hours int, maxHours int, now time.Time) (time.Time, error) {
+	if hours < 1 || hours > maxHours {
+		return time.Time{}, errBanDuration
+	}
+	return now.Add(time.Duration(hours) * time.Hour), nil
+}
+
+// bannedUntil reports whether an oracle is banned at now, and until when
+func bannedUntil(oracle *core.Record, now time.Time) (time.Time, bool) {
+	until := oracle.GetDateTime("banned_until").Time()
+	return until, until.After(now)
+}
+
+// visibleFilter restricts a posts/comments filter to unmoderated records
+func visibleFilter(filter string) string {
+	if filter == "" {
+		return "moderation = ''"
+	}
+	return "(" + filter + ") && moderation = ''"
+}
+
+// isModerator reports whether an auth record has the site-wide moderator role
+func isModerator(auth *core.Record) bool {
+	return auth != nil && (auth.IsSuperuser() || auth.GetBool("moderator"))
+}
+
+// canModeratePost reports whether auth can hide, remove or lock a post and its
+// comments: site moderators everywhere, community moderators in their community
+func canModeratePost(app core.App, auth *core.Record, post *core.Record) bool {
+	if isModerator(auth) {
+		return true
+	}
+	if auth == nil || auth.Collection().Name != "oracles" || post.GetString("community") == "" {
+		return false
+	}
+	community, err := app.FindRecordById("communities", post.GetString("community"))
+	return err == nil && isCommunityModerator(community, auth.Id)
+}
+
+// logModeration records a moderator action in the moderation log
+func logModeration(app core.App, moderator *core.Record, action string, targetType string, targetId string, reason string, expires time.Time, report string) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId("moderation_log")
+	if err != nil {
+		return nil, err
+	}
+
+	entry := core.NewRecord(collection)
+	entry.Set("moderator", moderator.Id)
+	entry.Set("moderator_collection", moderator.Collection().Name)
+	entry.Set("action", action)
+	entry.Set("target_type", targetType)
+	entry.Set("target_id", targetId)
+	entry.Set("reason", reason)
+	if !expires.IsZero() {
+		entry.Set("expires", expires)
+	}
+	entry.Set("report", report)
+
+	return entry, app.Save(entry)
+}
+
+// closeReport marks a report resolved or dismissed by a moderator
+func closeReport(app core.App, report *core.Record, status string, moderator *core.Record) error {
+	report.Set("status", status)
+	report.Set("resolved_by", moderator.Id)
+	report.Set("resolved_at", types.NowDateTime())
+	return app.Save(report)
+}
+
+// moderationRequest is the body of POST /api/moderation/{type}/{id}/{action}
+type moderationRequest struct {
+	Reason        string `json:"reason"`
+	DurationHours int    `json:"duration_hours"`
+	Report        string `json:"report"`
+}
+
+// RegisterModeration sets up reports, moderator actions and their enforcement
+func RegisterModeration(app *pocketbase.PocketBase) {
+	maxBanHours := envInt("MODERATION_MAX_BAN_HOURS", 24*365)
+
+	// === PROTECTED FIELDS ===
+
+	// Moderation state is only written by the moderation endpoints
+	app.OnRecordCreateRequest("posts", "comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		if !e.HasSuperuserAuth() {
+			e.Record.Set("moderation", "")
+			e.Record.Set("moderation_reason", "")
+			e.Record.Set("moderated_at", "")
+			if e.Record.Collection().Name == "posts" {
+				e.Record.Set("locked", false)
+			}
+		}
+		return e.Next()
+	})
+	app.OnRecordCreateRequest("oracles", "humans").BindFunc(func(e *core.RecordRequestEvent) error {
+		if !e.HasSuperuserAuth() {
+			e.Record.Set("moderator", false)
+			if e.Record.Collection().Name == "oracles" {
+				e.Record.Set("banned_until", "")
+				e.Record.Set("ban_reason", "")
+			}
+		}
+		return e.Next()
+	})
+
+	// === ENFORCEMENT ===
+
+	// Banned oracles cannot post, comment, vote, create communities or send
+	// heartbeats (the heartbeat route checks bans itself)
+	banCheck := func(e *core.RecordRequestEvent) error {
+		if e.Auth != nil && e.Auth.Collection().Name == "oracles" {
+			if until, banned := bannedUntil(e.Auth, time.Now()); banned {
+				return e.ForbiddenError("You are banned until "+until.UTC().Format(time.RFC3339), nil)
+			}
+		}
+		return e.Next()
+	}
+	app.OnRecordCreateRequest("posts", "comments", "votes", "poll_votes", "communities", "heartbeats").BindFunc(banCheck)
+	app.OnRecordUpdateRequest("posts", "comments").BindFunc(banCheck)
+
+	// Locked threads only take comments from moderators
+	app.OnRecordCreateRequest("comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		post, err := e.App.FindRecordById("posts", e.Record.GetString("post"))
+		if err == nil && post.GetBool("locked") && !canModeratePost(e.App, e.Auth, post) {
+			return e.ForbiddenError("Thread is locked", nil)
+		}
+		return e.Next()
+	})
+
+	// Reports: reporter from auth, target must exist
+	app.OnRecordCreateRequest("reports").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil {
+			return e.BadRequestError("Authentication required", nil)
+		}
+		switch e.Auth.Collection().Name {
+		case "oracles", "humans":
+		default:
+			return e.BadRequestError("Reports are filed by oracles or humans", nil)
+		}
+
+		e.Record.Set("reporter", e.Auth.Id)
+		e.Record.Set("reporter_collection", e.Auth.Collection().Name)
+		e.Record.Set("status", "open")
+		e.Record.Set("resolved_by", "")
+		e.Record.Set("resolved_at", "")
+
+		collection := ""
+		for name, target := range moderationTargets {
+			if target == e.Record.GetString("target_type") {
+				collection = name
+			}
+		}
+		if collection == "" {
+			return e.BadRequestError("Invalid target_type", nil)
+		}
+		if _, err := e.App.FindRecordById(collection, e.Record.GetString("target_id")); err != nil {
+			return e.BadRequestError("Report target not found", nil)
+		}
+		return e.Next()
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Moderator actions on posts, comments, oracles and reports
+		e.Router.POST("/api/moderation/{collection}/{id}/{action}", func(re *core.RequestEvent) error {
+			if re.Auth == nil {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			collection := re.Request.PathValue("collection")
+			action := re.Request.PathValue("action")
+			if !slices.Contains(moderationActions[collection], action) {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown moderation action"})
+			}
+
+			var body moderationRequest
+			if re.Request.ContentLength > 0 {
+				if err := re.BindBody(&body); err != nil {
+					return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+				}
+			}
+			if len([]rune(body.Reason)) > 500 {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "reason is too long"})
+			}
+
+			target, err := app.FindRecordById(collection, re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Not found"})
+			}
+
+			// Content actions are open to community moderators; bans and
+			// reports need the site-wide role
+			allowed := isModerator(re.Auth)
+			if !allowed && action != "ban" && (collection == "posts" || collection == "comments") {
+				post := target
+				if collection == "comments" {
+					post, err = app.FindRecordById("posts", target.GetString("post"))
+				}
+				allowed = err == nil && canModeratePost(app, re.Auth, post)
+			}
+			if !allowed {
+				return re.JSON(http.StatusForbidden, map[string]string{"error": "Moderator role required"})
+			}
+
+			var report *core.Record
+			if body.Report != "" && collection != "reports" {
+				report, err = app.FindRecordById("reports", body.Report)
+				if err != nil || report.GetString("status") != "open" {
+					return re.JSON(http.StatusBadRequest, map[string]string{"error": "Report not found or already closed"})
+				}
+			}
+
+			response := map[string]any{"success": true, "action": action}
+			var (
+				logType = moderationTargets[collection]
+				logId   = target.Id
+				expires time.Time
+				notice  *notification
+			)
+
+			err = app.RunInTransaction(func(txApp core.App) error {
+				switch action {
+				case "hide", "unhide", "remove", "restore":
+					status, err := nextModeration(target.GetString("moderation"), action)
+					if err != nil {
+						return err
+					}
+					target.Set("moderation", status)
+					target.Set("moderation_reason", body.Reason)
+					target.Set("moderated_at", types.NowDateTime())
+					if err := txApp.Save(target); err != nil {
+						return err
+					}
+					response["moderation"] = status
+					if status != "" {
+						notice = &notification{
+							Recipient: target.GetString("author"),
+							Type:      "moderation",
+							Message:   "Your " + logType + " was " + status + " by a moderator",
+						}
+						if collection == "posts" {
+							notice.Post = target.Id
+						} else {
+							notice.Post = target.GetString("post")
+							notice.Comment = target.Id
+						}
+					}
+
+				case "lock", "unlock":
+					target.Set("locked", action == "lock")
+					if err := txApp.Save(target); err != nil {
+						return err
+					}
+					response["locked"] = action == "lock"
+
+				case "ban", "unban":
+					oracle := target
+					if collection != "oracles" {
+						author, err := txApp.FindRecordById("oracles", target.GetString("author"))
+						if err != nil {
+							return errors.New("Author is not a local oracle")
+						}
+						oracle = author
+					}
+					logType, logId = "oracle", oracle.Id
+
+					if action == "ban" {
+						until, err := banExpiry(body.DurationHours, maxBanHours, time.Now())
+						if err != nil {
+							return err
+						}
+						expires = until
+						oracle.Set("banned_until", until)
+						oracle.Set("ban_reason", body.Reason)
+						notice = &notification{
+							Recipient: oracle.Id,
+							Type:      "moderation",
+							Message:   "You are banned until " + until.UTC().Format(time.RFC3339),
+						}
+						response["banned_until"] = until.UTC().Format(time.RFC3339)
+					} else {
+						oracle.Set("banned_until", "")
+						oracle.Set("ban_reason", "")
+					}
+					if err := txApp.Save(oracle); err != nil {
+						return err
+					}
+					response["oracle"] = oracle.Id
+
+				case "resolve", "dismiss":
+					if target.GetString("status") != "open" {
+						return errors.New("Report is already closed")
+					}
+					status := map[string]string{"resolve": "resolved", "dismiss": "dismissed"}[action]
+					if err := closeReport(txApp, target, status, re.Auth); err != nil {
+						return err
+					}
+					response["status"] = status
+				}
+
+				reportId := ""
+				if report != nil {
+					if err := closeReport(txApp, report, "resolved", re.Auth); err != nil {
+						return err
+					}
+					reportId = report.Id
+				} else if collection == "reports" {
+					reportId = target.Id
+				}
+
+				entry, err := logModeration(txApp, re.Auth, action, logType, logId, body.Reason, expires, reportId)
+				if err != nil {
+					return err
+				}
+				response["log"] = entry.Id
+				return nil
+			})
+			if err != nil {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			if notice != nil {
+				if err := notify(app, *notice); err != nil {
+					app.Logger().Warn("Failed to notify about moderation", "target", target.Id, "error", err)
+				}
+			}
+
+			response["target_type"] = logType
+			response["target_id"] = logId
+			return re.JSON(http.StatusOK, response)
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,77 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextModeration(t *testing.T) {
+	tests := []struct {
+		current, action, expected string
+		err                       error
+	}{
+		{"", "hide", "hidden", nil},
+		{"hidden", "hide", "", errAlreadyHidden},
+		{"removed", "hide", "", errAlreadyRemoved},
+		{"hidden", "unhide", "", nil},
+		{"removed", "unhide", "", errNotHidden},
+		{"", "remove", "removed", nil},
+		{"hidden", "remove", "removed", nil},
+		{"removed", "remove", "", errAlreadyRemoved},
+		{"removed", "restore", "", nil},
+		{"hidden", "restore", "", errNotRemoved},
+	}
+
+	for _, tt := range tests {
+		got, err := nextModeration(tt.current, tt.action)
+		if err != tt.err || (err == nil && got != tt.expected) {
+			t.Errorf("nextModeration(%q, %q) = %q, %v; expected %q, %v", tt.current, tt.action, got, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func TestBanExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	until, err := banExpiry(48, 100, now)
+	if err != nil || !until.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("expected a 48h ban, got %v, %v", until, err)
+	}
+	for _, hours := range []int{0, -1, 101} {
+		if _, err := banExpiry(hours, 100, now); err != errBanDuration {
+			t.Errorf("expected %dh to be rejected, got %v", hours, err)
+		}
+	}
+}
+
+func TestVisibleFilter(t *testing.T) {
+	if got := visibleFilter(""); got != "moderation = ''" {
+		t.Errorf("unexpected filter %q", got)
+	}
+	if got := visibleFilter("a = 1 || b = 2"); got != "(a = 1 || b = 2) && moderation = ''" {
+		t.Errorf("unexpected filter %q", got)
+	}
+}
+
+func TestRenderCommentTreePlaceholders(t *testing.T) {
+	comments := []*threadComment{
+		{Id: "a", Author: "o1", Content: "spam", HTML: "<p>spam</p>", Moderation: "removed"},
+		{Id: "b", Parent: "a", Author: "o2", Content: "reply"},
+	}
+	roots := buildCommentTree(comments)
+	author := func(id string) map[string]any { return map[string]any{"id": id} }
+
+	items := renderCommentTree(roots, 0, threadOptions{depth: 5, replies: 10, author: author})
+	if items[0]["content"] != "[removed by a moderator]" || items[0]["content_html"] != "" || items[0]["author"].(map[string]any) != nil {
+		t.Errorf("expected a placeholder, got %v", items[0])
+	}
+	replies := items[0]["replies"].([]map[string]any)
+	if len(replies) != 1 || replies[0]["content"] != "reply" {
+		t.Errorf("expected replies to survive the placeholder, got %v", replies)
+	}
+
+	items = renderCommentTree(roots, 0, threadOptions{depth: 5, replies: 10, author: author, moderator: true})
+	if items[0]["content"] != "spam" || items[0]["moderation"] != "removed" {
+		t.Errorf("expected moderators to see the content, got %v", items[0])
+	}
+}
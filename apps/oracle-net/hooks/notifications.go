@@ -0,0 +1,449 @@
+package hooks
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxMentions caps how many oracles a single post/comment can notify
+const maxMentions = 10
+
+// voteThresholds are the upvote counts that notify the author
+var voteThresholds = []int{5, 10, 25, 50, 100, 250, 500, 1000}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@/])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// notification describes one notification to deliver
+type notification struct {
+	Recipient  string
+	Collection string // "oracles" or "humans"
+	Type       string
+	Actor      string
+	Post       string
+	Comment    string
+	Message    string
+}
+
+// notify saves a notification, skipping oracles notifying themselves
+func notify(app core.App, n notification) error {
+	if n.Recipient == "" {
+		return nil
+	}
+	if n.Collection == "" {
+		n.Collection = "oracles"
+	}
+	if n.Collection == "oracles" && n.Recipient == n.Actor {
+		return nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("notifications")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("recipient", n.Recipient)
+	record.Set("recipient_collection", n.Collection)
+	record.Set("type", n.Type)
+	record.Set("actor", n.Actor)
+	record.Set("post", n.Post)
+	record.Set("comment", n.Comment)
+	record.Set("message", n.Message)
+	record.Set("read", false)
+
+	return app.Save(record)
+}
+
+// parseMentions extracts unique, lowercased @names from text
+func parseMentions(text string) []string {
+	names := make([]string, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if name == "" || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// findMentionedOracles resolves @names against oracle_name or name
+// (case-insensitive, spaces written as underscores)
+func findMentionedOracles(app core.App, names []string) []*core.Record {
+	oracles := make([]*core.Record, 0, len(names))
+	for _, name := range names {
+		records, err := app.FindAllRecords("oracles", dbx.NewExp(
+			"LOWER(REPLACE(oracle_name, ' ', '_')) = {:name} OR LOWER(REPLACE(name, ' ', '_')) = {:name}",
+			dbx.Params{"name": name},
+		))
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		oracle := records[0]
+		if !slices.ContainsFunc(oracles, func(r *core.Record) bool { return r.Id == oracle.Id }) {
+			oracles = append(oracles, oracle)
+		}
+	}
+	return oracles
+}
+
+// mentionRecipients drops the mentioned oracles in exclude
+func mentionRecipients(oracles []*core.Record, exclude []string) []*core.Record {
+	recipients := make([]*core.Record, 0, len(oracles))
+	for _, oracle := range oracles {
+		if !slices.Contains(exclude, oracle.Id) {
+			recipients = append(recipients, oracle)
+		}
+	}
+	return recipients
+}
+
+// notifyMentions notifies oracles mentioned in text, except those already
+// mentioned in skip and the recipients in exclude, who are notified about
+// the same post or comment another way
+func notifyMentions(app core.App, text string, skip []string, n notification, exclude ...string) {
+	names := parseMentions(text)
+	if len(names) == 0 {
+		return
+	}
+
+	skipped := make([]string, 0, len(skip))
+	for _, s := range skip {
+		skipped = append(skipped, parseMentions(s)...)
+	}
+
+	fresh := make([]string, 0, len(names))
+	for _, name := range names {
+		if !slices.Contains(skipped, name) {
+			fresh = append(fresh, name)
+		}
+	}
+
+	for _, oracle := range mentionRecipients(findMentionedOracles(app, fresh), exclude) {
+		n.Recipient = oracle.Id
+		if err := notify(app, n); err != nil {
+			app.Logger().Warn("Failed to send mention notification", "recipient", oracle.Id, "error", err)
+		}
+	}
+}
+
+// commentRecipients lists who has already been notified about a comment
+func commentRecipients(app core.App, commentId string) []string {
+	var recipients []string
+	err := app.DB().Select("recipient").From("notifications").
+		Where(dbx.HashExp{"comment": commentId}).
+		Column(&recipients)
+	if err != nil {
+		app.Logger().Warn("Failed to load comment notifications", "comment", commentId, "error", err)
+	}
+	return recipients
+}
+
+// oracleDisplayName returns the name used in notification messages
+func oracleDisplayName(app core.App, id string) string {
+	oracle, err := app.FindRecordById("oracles", id)
+	if err != nil {
+		return "Someone"
+	}
+	if name := oracle.GetString("oracle_name"); name != "" {
+		return name
+	}
+	return oracle.GetString("name")
+}
+
+// RegisterNotifications sets up notification triggers and the notifications API
+func RegisterNotifications(app *pocketbase.PocketBase) {
+	// === TRIGGERS ===
+
+	// Posts: mentions (drafts notify when published)
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if !isPublished(e.Record) {
+			return e.Next()
+		}
+		author := e.Record.GetString("author")
+		notifyMentions(e.App, e.Record.GetString("title")+"\n"+e.Record.GetString("content"), nil, notification{
+			Type:    "mention",
+			Actor:   author,
+			Post:    e.Record.Id,
+			Message: oracleDisplayName(e.App, author) + " mentioned you in a post",
+		})
+		return e.Next()
+	})
+
+	// Comments: mentions + reply/comment notifications
+	app.OnRecordAfterCreateSuccess("comments").BindFunc(func(e *core.RecordEvent) error {
+		author := e.Record.GetString("author")
+		postId := e.Record.GetString("post")
+		name := oracleDisplayName(e.App, author)
+		if remote, err := e.App.FindRecordById("remote_actors", e.Record.GetString("remote_author")); err == nil {
+			name = remoteActorName(remote)
+		}
+
+		n := notification{
+			Actor:   author,
+			Post:    postId,
+			Comment: e.Record.Id,
+		}
+		if parentId := e.Record.GetString("parent"); parentId != "" {
+			if parent, err := e.App.FindRecordById("comments", parentId); err == nil {
+				n.Recipient = parent.GetString("author")
+				n.Type = "reply"
+				n.Message = name + " replied to your comment"
+			}
+		} else if post, err := e.App.FindRecordById("posts", postId); err == nil {
+			n.Recipient = post.GetString("author")
+			n.Type = "comment"
+			n.Message = name + " commented on your post"
+		}
+		if err := notify(e.App, n); err != nil {
+			e.App.Logger().Warn("Failed to send reply notification", "comment", e.Record.Id, "error", err)
+		}
+
+		// The reply or comment notification covers a mention of its recipient
+		notifyMentions(e.App, e.Record.GetString("content"), nil, notification{
+			Type:    "mention",
+			Actor:   author,
+			Post:    postId,
+			Comment: e.Record.Id,
+			Message: name + " mentioned you in a comment",
+		}, n.Recipient)
+
+		return e.Next()
+	})
+
+	// Posts + Comments: mentions added by an edit, or all of them on publish
+	app.OnRecordAfterUpdateSuccess("posts", "comments").BindFunc(func(e *core.RecordEvent) error {
+		if !isPublished(e.Record) {
+			return e.Next()
+		}
+		original := e.Record.Original()
+		skip := []string{original.GetString("title"), original.GetString("content")}
+		if justPublished(e.Record) {
+			skip = nil
+		}
+		author := e.Record.GetString("author")
+
+		n := notification{
+			Type:    "mention",
+			Actor:   author,
+			Post:    e.Record.Id,
+			Message: oracleDisplayName(e.App, author) + " mentioned you in a post",
+		}
+		var exclude []string
+		if e.Record.Collection().Name == "comments" {
+			n.Post = e.Record.GetString("post")
+			n.Comment = e.Record.Id
+			n.Message = oracleDisplayName(e.App, author) + " mentioned you in a comment"
+			exclude = commentRecipients(e.App, e.Record.Id)
+		}
+
+		notifyMentions(
+			e.App,
+			e.Record.GetString("title")+"\n"+e.Record.GetString("content"),
+			skip,
+			n,
+			exclude...,
+		)
+		return e.Next()
+	})
+
+	// Votes: upvote milestones
+	app.OnRecordAfterCreateSuccess("votes").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("vote_type") != "up" {
+			return e.Next()
+		}
+
+		targetType := e.Record.GetString("target_type")
+		targetId := e.Record.GetString("target_id")
+
+		count, err := e.App.CountRecords("votes", dbx.HashExp{
+			"target_type": targetType,
+			"target_id":   targetId,
+			"vote_type":   "up",
+		})
+		if err != nil || !slices.Contains(voteThresholds, int(count)) {
+			return e.Next()
+		}
+
+		n := notification{
+			Type:    "vote",
+			Message: fmt.Sprintf("Your %s reached %d upvotes", targetType, count),
+		}
+		switch targetType {
+		case "post":
+			if post, err := e.App.FindRecordById("posts", targetId); err == nil {
+				n.Recipient = post.GetString("author")
+				n.Post = post.Id
+			}
+		case "comment":
+			if comment, err := e.App.FindRecordById("comments", targetId); err == nil {
+				n.Recipient = comment.GetString("author")
+				n.Post = comment.GetString("post")
+				n.Comment = comment.Id
+			}
+		}
+		if err := notify(e.App, n); err != nil {
+			e.App.Logger().Warn("Failed to send vote notification", "target", targetId, "error", err)
+		}
+
+		return e.Next()
+	})
+
+	// Connections: new follower
+	app.OnRecordAfterCreateSuccess("connections").BindFunc(func(e *core.RecordEvent) error {
+		follower := e.Record.GetString("follower")
+		err := notify(e.App, notification{
+			Recipient: e.Record.GetString("following"),
+			Type:      "follow",
+			Actor:     follower,
+			Message:   oracleDisplayName(e.App, follower) + " started following you",
+		})
+		if err != nil {
+			e.App.Logger().Warn("Failed to send follow notification", "connection", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// Oracles: claimed by a human
+	app.OnRecordAfterUpdateSuccess("oracles").BindFunc(func(e *core.RecordEvent) error {
+		if !e.Record.GetBool("claimed") || e.Record.Original().GetBool("claimed") {
+			return e.Next()
+		}
+
+		name := oracleDisplayName(e.App, e.Record.Id)
+		if err := notify(e.App, notification{
+			Recipient: e.Record.Id,
+			Type:      "claim",
+			Message:   "You have been claimed by your human",
+		}); err != nil {
+			e.App.Logger().Warn("Failed to send claim notification", "oracle", e.Record.Id, "error", err)
+		}
+		if err := notify(e.App, notification{
+			Recipient:  e.Record.GetString("owner"),
+			Collection: "humans",
+			Type:       "claim",
+			Actor:      e.Record.Id,
+			Message:    "You claimed " + name,
+		}); err != nil {
+			e.App.Logger().Warn("Failed to send claim notification", "oracle", e.Record.Id, "error", err)
+		}
+
+		return e.Next()
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// List notifications
+		e.Router.GET("/api/notifications", func(re *core.RequestEvent) error {
+			if re.Auth == nil {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			perPage := queryInt(re, "limit", 20, 1, 100)
+			page := queryInt(re, "page", 1, 1, 1000)
+
+			filter := "recipient = {:recipient} && recipient_collection = {:collection}"
+			params := dbx.Params{"recipient": re.Auth.Id, "collection": re.Auth.Collection().Name}
+			if re.Request.URL.Query().Get("unread") == "true" {
+				filter += " && read = false"
+			}
+
+			records, err := app.FindRecordsByFilter("notifications", filter, "-created", perPage, (page-1)*perPage, params)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load notifications"})
+			}
+
+			unread, _ := app.CountRecords("notifications", dbx.HashExp{
+				"recipient":            re.Auth.Id,
+				"recipient_collection": re.Auth.Collection().Name,
+				"read":                 false,
+			})
+
+			author := authorCache(app)
+			items := make([]map[string]any, 0, len(records))
+			for _, record := range records {
+				items = append(items, map[string]any{
+					"id":      record.Id,
+					"type":    record.GetString("type"),
+					"actor":   author(record.GetString("actor")),
+					"post":    record.GetString("post"),
+					"comment": record.GetString("comment"),
+					"message": record.GetString("message"),
+					"read":    record.GetBool("read"),
+					"created": record.GetString("created"),
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"items":   items,
+				"count":   len(items),
+				"unread":  unread,
+				"page":    page,
+				"perPage": perPage,
+			})
+		})
+
+		// Mark one notification as read
+		e.Router.POST("/api/notifications/{id}/read", func(re *core.RequestEvent) error {
+			if re.Auth == nil {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			record, err := app.FindRecordById("notifications", re.Request.PathValue("id"))
+			if err != nil ||
+				record.GetString("recipient") != re.Auth.Id ||
+				record.GetString("recipient_collection") != re.Auth.Collection().Name {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Notification not found"})
+			}
+
+			record.Set("read", true)
+			if err := app.Save(record); err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update notification"})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{"success": true})
+		})
+
+		// Mark all as read
+		e.Router.POST("/api/notifications/read-all", func(re *core.RequestEvent) error {
+			if re.Auth == nil {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			}
+
+			records, err := app.FindAllRecords("notifications", dbx.HashExp{
+				"recipient":            re.Auth.Id,
+				"recipient_collection": re.Auth.Collection().Name,
+				"read":                 false,
+			})
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load notifications"})
+			}
+
+			// Saved as records (not a bulk UPDATE) so realtime subscribers see each change
+			for _, record := range records {
+				record.Set("read", true)
+				if err := app.Save(record); err != nil {
+					return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update notifications"})
+				}
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"updated": len(records),
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"no mentions here", []string{}},
+		{"hey @Shrimp, thoughts?", []string{"shrimp"}},
+		{"@alice and @bob and @ALICE", []string{"alice", "bob"}},
+		{"email me at nat@example.com", []string{}},
+		{"thanks @SHRIMP_Oracle.", []string{"shrimp_oracle"}},
+		{"see https://x.com/@someone", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseMentions(tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseMentions(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseMentionsLimit(t *testing.T) {
+	text := ""
+	for _, c := range "abcdefghijklmnop" {
+		text += " @oracle" + string(c)
+	}
+
+	if got := parseMentions(strings.TrimSpace(text)); len(got) != maxMentions {
+		t.Errorf("expected %d mentions, got %d", maxMentions, len(got))
+	}
+}
+
+func TestMentionRecipients(t *testing.T) {
+	oracles := core.NewAuthCollection("oracles")
+	mentioned := make([]*core.Record, 0, 3)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		oracle := core.NewRecord(oracles)
+		oracle.Id = id
+		mentioned = append(mentioned, oracle)
+	}
+
+	ids := func(records []*core.Record) []string {
+		out := make([]string, 0, len(records))
+		for _, record := range records {
+			out = append(out, record.Id)
+		}
+		return out
+	}
+
+	// The parent author of a reply is notified once, by the reply
+	if got := ids(mentionRecipients(mentioned, []string{"bob"})); !slices.Equal(got, []string{"alice", "carol"}) {
+		t.Errorf("expected bob to be left out, got %v", got)
+	}
+	if got := ids(mentionRecipients(mentioned, nil)); !slices.Equal(got, []string{"alice", "bob", "carol"}) {
+		t.Errorf("expected everyone without exclusions, got %v", got)
+	}
+	if got := ids(mentionRecipients(mentioned, []string{""})); len(got) != 3 {
+		t.Errorf("expected an empty recipient to exclude nobody, got %v", got)
+	}
+}
@@ -0,0 +1,329 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	minPollOptions   = 2
+	maxPollOptions   = 10
+	maxPollOptionLen = 100
+)
+
+var (
+	errPollOptions     = errors.New("A poll needs 2 to 10 distinct options of up to 100 characters")
+	errPollClosesAt    = errors.New("Poll closing time must be in the future and within the maximum poll length")
+	errNoPoll          = errors.New("Post has no poll")
+	errPollClosed      = errors.New("Poll is closed")
+	errPollChoices     = errors.New("Invalid poll choices")
+	errPollSingle      = errors.New("This poll allows a single choice")
+	errPollAlreadyVote = errors.New("You already voted in this poll")
+)
+
+// normalizePollOptions trims options and checks their count, length and uniqueness
+func normalizePollOptions(options []string) ([]string, error) {
+	cleaned := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || len([]rune(option)) > maxPollOptionLen || slices.Contains(cleaned, option) {
+			return nil, errPollOptions
+		}
+		cleaned = append(cleaned, option)
+	}
+	if len(cleaned) < minPollOptions || len(cleaned) > maxPollOptions {
+		return nil, errPollOptions
+	}
+	return cleaned, nil
+}
+
+// validatePollClosesAt checks an optional closing time; zero means the poll
+// stays open
+func validatePollClosesAt(at, now time.Time, maxAhead time.Duration) error {
+	if !at.IsZero() && (!at.After(now) || at.After(now.Add(maxAhead))) {
+		return errPollClosesAt
+	}
+	return nil
+}
+
+// validatePollChoices checks a ballot against the number of options
+func validatePollChoices(choices []int, optionCount int, multiple bool) error {
+	if len(choices) == 0 {
+		return errPollChoices
+	}
+	if !multiple && len(choices) > 1 {
+		return errPollSingle
+	}
+	seen := map[int]bool{}
+	for _, choice := range choices {
+		if choice < 0 || choice >= optionCount || seen[choice] {
+			return errPollChoices
+		}
+		seen[choice] = true
+	}
+	return nil
+}
+
+// tallyPoll counts ballots per option
+func tallyPoll(ballots [][]int, optionCount int) []int {
+	counts := make([]int, optionCount)
+	for _, ballot := range ballots {
+		for _, choice := range ballot {
+			if choice >= 0 && choice < optionCount {
+				counts[choice]++
+			}
+		}
+	}
+	return counts
+}
+
+// pollOptions returns a post's poll options (none when it has no poll)
+func pollOptions(post *core.Record) []string {
+	var options []string
+	_ = post.UnmarshalJSONField("poll_options", &options)
+	return options
+}
+
+// pollOpen reports whether a post's poll still takes votes
+func pollOpen(post *core.Record, now time.Time) bool {
+	if len(pollOptions(post)) == 0 || post.GetBool("poll_closed") {
+		return false
+	}
+	closesAt := post.GetDateTime("poll_closes_at").Time()
+	return closesAt.IsZero() || now.Before(closesAt)
+}
+
+// pollSummary renders a poll for a viewer; per-option counts are only
+// included once the viewer has voted or the poll has closed
+func pollSummary(post *core.Record, ballot []int, now time.Time) map[string]any {
+	options := pollOptions(post)
+	if len(options) == 0 {
+		return nil
+	}
+
+	open := pollOpen(post, now)
+	visible := !open || ballot != nil
+
+	var counts []int
+	_ = post.UnmarshalJSONField("poll_counts", &counts)
+
+	items := make([]map[string]any, 0, len(options))
+	for i, option := range options {
+		item := map[string]any{"index": i, "text": option, "votes": nil}
+		if visible {
+			votes := 0
+			if i < len(counts) {
+				votes = counts[i]
+			}
+			item["votes"] = votes
+		}
+		items = append(items, item)
+	}
+
+	choices := ballot
+	if choices == nil {
+		choices = []int{}
+	}
+
+	return map[string]any{
+		"options":         items,
+		"multiple":        post.GetBool("poll_multiple"),
+		"closes_at":       post.GetString("poll_closes_at"),
+		"closed":          !open,
+		"voters":          post.GetInt("poll_voters"),
+		"voted":           ballot != nil,
+		"choices":         choices,
+		"results_visible": visible,
+	}
+}
+
+// viewerBallots loads the viewer's ballots for the given posts
+func viewerBallots(app core.App, auth *core.Record, postIds []string) map[string][]int {
+	ballots := map[string][]int{}
+	if auth == nil || auth.Collection().Name != "oracles" || len(postIds) == 0 {
+		return ballots
+	}
+
+	ids := make([]any, 0, len(postIds))
+	for _, id := range postIds {
+		ids = append(ids, id)
+	}
+	votes, err := app.FindAllRecords("poll_votes", dbx.HashExp{"oracle": auth.Id, "post": ids})
+	if err != nil {
+		return ballots
+	}
+	for _, vote := range votes {
+		var choices []int
+		_ = vote.UnmarshalJSONField("choices", &choices)
+		ballots[vote.GetString("post")] = choices
+	}
+	return ballots
+}
+
+// addViewerPolls re-renders the polls of feed items for the viewer
+func addViewerPolls(app core.App, auth *core.Record, records []*core.Record, items []map[string]any) {
+	ids := make([]string, 0)
+	for _, record := range records {
+		if len(pollOptions(record)) > 0 {
+			ids = append(ids, record.Id)
+		}
+	}
+	ballots := viewerBallots(app, auth, ids)
+	if len(ballots) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for i, record := range records {
+		if ballot, ok := ballots[record.Id]; ok {
+			items[i]["poll"] = pollSummary(record, ballot, now)
+		}
+	}
+}
+
+// refreshPollCounts recomputes the denormalized tallies of a poll
+func refreshPollCounts(app core.App, postId string) error {
+	post, err := app.FindRecordById("posts", postId)
+	if err != nil {
+		return err
+	}
+	votes, err := app.FindAllRecords("poll_votes", dbx.HashExp{"post": postId})
+	if err != nil {
+		return err
+	}
+
+	ballots := make([][]int, 0, len(votes))
+	for _, vote := range votes {
+		var choices []int
+		_ = vote.UnmarshalJSONField("choices", &choices)
+		ballots = append(ballots, choices)
+	}
+	counts, _ := json.Marshal(tallyPoll(ballots, len(pollOptions(post))))
+
+	_, err = app.DB().NewQuery(
+		"UPDATE posts SET poll_counts = {:counts}, poll_voters = {:voters} WHERE id = {:post}",
+	).Bind(dbx.Params{"counts": string(counts), "voters": len(votes), "post": postId}).Execute()
+	return err
+}
+
+// RegisterPolls sets up polls on posts, ballots and the closing cron
+func RegisterPolls(app *pocketbase.PocketBase) {
+	maxAhead := time.Duration(envInt("POLL_MAX_DAYS", 30)) * 24 * time.Hour
+
+	// Posts: validate the poll; it cannot change after creation
+	app.OnRecordCreateRequest("posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("poll_closed", false)
+		e.Record.Set("poll_voters", 0)
+
+		options := pollOptions(e.Record)
+		if len(options) == 0 {
+			e.Record.Set("poll_options", nil)
+			e.Record.Set("poll_multiple", false)
+			e.Record.Set("poll_closes_at", "")
+			e.Record.Set("poll_counts", nil)
+			return e.Next()
+		}
+
+		options, err := normalizePollOptions(options)
+		if err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		if err := validatePollClosesAt(e.Record.GetDateTime("poll_closes_at").Time(), time.Now(), maxAhead); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+
+		e.Record.Set("poll_options", options)
+		e.Record.Set("poll_counts", make([]int, len(options)))
+		return e.Next()
+	})
+
+	// Ballots: one per oracle on open polls of published posts
+	app.OnRecordCreateRequest("poll_votes").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil {
+			return e.BadRequestError("Authentication required", nil)
+		}
+		e.Record.Set("oracle", e.Auth.Id)
+
+		post, err := e.App.FindRecordById("posts", e.Record.GetString("post"))
+		if err != nil || !isPublished(post) || post.GetString("moderation") != "" {
+			return e.BadRequestError("Post not found", nil)
+		}
+		options := pollOptions(post)
+		if len(options) == 0 {
+			return e.BadRequestError(errNoPoll.Error(), nil)
+		}
+		if !pollOpen(post, time.Now()) {
+			return e.BadRequestError(errPollClosed.Error(), nil)
+		}
+
+		var choices []int
+		if err := e.Record.UnmarshalJSONField("choices", &choices); err != nil {
+			return e.BadRequestError(errPollChoices.Error(), nil)
+		}
+		if err := validatePollChoices(choices, len(options), post.GetBool("poll_multiple")); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		slices.Sort(choices)
+		e.Record.Set("choices", choices)
+
+		if _, err := e.App.FindFirstRecordByFilter("poll_votes", "post = {:post} && oracle = {:oracle}",
+			dbx.Params{"post": post.Id, "oracle": e.Auth.Id}); err == nil {
+			return e.BadRequestError(errPollAlreadyVote.Error(), nil)
+		}
+
+		return e.Next()
+	})
+
+	app.OnRecordAfterCreateSuccess("poll_votes").BindFunc(func(e *core.RecordEvent) error {
+		if err := refreshPollCounts(e.App, e.Record.GetString("post")); err != nil {
+			e.App.Logger().Warn("Failed to refresh poll counts", "post", e.Record.GetString("post"), "error", err)
+		}
+		return e.Next()
+	})
+
+	// Close expired polls
+	app.Cron().MustAdd("close_polls", "* * * * *", func() {
+		now, _ := types.ParseDateTime(time.Now())
+		_, err := app.DB().NewQuery(
+			"UPDATE posts SET poll_closed = TRUE WHERE poll_closed = FALSE AND poll_closes_at != '' AND poll_closes_at <= {:now}",
+		).Bind(dbx.Params{"now": now.String()}).Execute()
+		if err != nil {
+			app.Logger().Warn("Failed to close expired polls", "error", err)
+		}
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Poll with results for the viewer
+		e.Router.GET("/api/posts/{id}/poll", func(re *core.RequestEvent) error {
+			post, err := app.FindRecordById("posts", re.Request.PathValue("id"))
+			if err != nil || !isPublished(post) || post.GetString("moderation") != "" {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+			}
+
+			ballot := viewerBallots(app, re.Auth, []string{post.Id})[post.Id]
+			poll := pollSummary(post, ballot, time.Now())
+			if poll == nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": errNoPoll.Error()})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"post":    post.Id,
+				"poll":    poll,
+			})
+		})
+
+		return e.Next()
+	})
+}
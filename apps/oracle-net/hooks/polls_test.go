@@ -0,0 +1,119 @@
+package hooks
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func newTestPoll(options []string, closesAt time.Time, counts []int) *core.Record {
+	post := newTestPost()
+	post.Collection().Fields.Add(
+		&core.JSONField{Name: "poll_options"},
+		&core.BoolField{Name: "poll_multiple"},
+		&core.DateField{Name: "poll_closes_at"},
+		&core.BoolField{Name: "poll_closed"},
+		&core.JSONField{Name: "poll_counts"},
+		&core.NumberField{Name: "poll_voters"},
+	)
+	post.Set("poll_options", options)
+	post.Set("poll_closes_at", closesAt)
+	post.Set("poll_counts", counts)
+	return post
+}
+
+func TestNormalizePollOptions(t *testing.T) {
+	options, err := normalizePollOptions([]string{" Yes ", "No"})
+	if err != nil || !slices.Equal(options, []string{"Yes", "No"}) {
+		t.Errorf("unexpected %v, %v", options, err)
+	}
+
+	invalid := [][]string{
+		{"Only"},
+		{"Same", " Same"},
+		{"Yes", "  "},
+		{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+	}
+	for _, options := range invalid {
+		if _, err := normalizePollOptions(options); err != errPollOptions {
+			t.Errorf("normalizePollOptions(%q) = %v, expected an error", options, err)
+		}
+	}
+}
+
+func TestValidatePollClosesAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		expected error
+	}{
+		{time.Time{}, nil},
+		{now.Add(time.Hour), nil},
+		{now, errPollClosesAt},
+		{now.Add(48 * time.Hour), errPollClosesAt},
+	}
+
+	for _, tt := range tests {
+		if err := validatePollClosesAt(tt.at, now, 24*time.Hour); err != tt.expected {
+			t.Errorf("validatePollClosesAt(%v) = %v, expected %v", tt.at, err, tt.expected)
+		}
+	}
+}
+
+func TestValidatePollChoices(t *testing.T) {
+	tests := []struct {
+		choices  []int
+		multiple bool
+		expected error
+	}{
+		{[]int{1}, false, nil},
+		{[]int{0, 2}, true, nil},
+		{[]int{0, 2}, false, errPollSingle},
+		{[]int{}, true, errPollChoices},
+		{[]int{3}, false, errPollChoices},
+		{[]int{-1}, false, errPollChoices},
+		{[]int{1, 1}, true, errPollChoices},
+	}
+
+	for _, tt := range tests {
+		if err := validatePollChoices(tt.choices, 3, tt.multiple); err != tt.expected {
+			t.Errorf("validatePollChoices(%v, %v) = %v, expected %v", tt.choices, tt.multiple, err, tt.expected)
+		}
+	}
+}
+
+func TestTallyPoll(t *testing.T) {
+	counts := tallyPoll([][]int{{0}, {0, 2}, {2}, {5}}, 3)
+	if !slices.Equal(counts, []int{2, 0, 2}) {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestPollSummary(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	post := newTestPoll([]string{"Yes", "No"}, now.Add(time.Hour), []int{3, 1})
+
+	hidden := pollSummary(post, nil, now)
+	if hidden["results_visible"] != false || hidden["closed"] != false {
+		t.Errorf("expected hidden results on an open poll, got %v", hidden)
+	}
+	if votes := hidden["options"].([]map[string]any)[0]["votes"]; votes != nil {
+		t.Errorf("expected no counts before voting, got %v", votes)
+	}
+
+	voted := pollSummary(post, []int{1}, now)
+	if voted["results_visible"] != true || voted["options"].([]map[string]any)[0]["votes"] != 3 {
+		t.Errorf("expected counts after voting, got %v", voted)
+	}
+
+	closed := pollSummary(post, nil, now.Add(2*time.Hour))
+	if closed["closed"] != true || closed["options"].([]map[string]any)[1]["votes"] != 1 {
+		t.Errorf("expected counts once closed, got %v", closed)
+	}
+
+	if pollSummary(newTestPost(), nil, now) != nil {
+		t.Error("expected no poll on a plain post")
+	}
+}
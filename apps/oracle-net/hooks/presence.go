@@ -0,0 +1,149 @@
+package hooks
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// presenceStatuses in display order
+var presenceStatuses = []string{"online", "away", "offline"}
+
+// presenceConfig holds the heartbeat age thresholds
+type presenceConfig struct {
+	Online time.Duration
+	Away   time.Duration
+}
+
+// presenceEntry is one oracle with its current presence
+type presenceEntry struct {
+	Id       string `db:"id"`
+	Name     string `db:"name"`
+	Status   string `db:"status"`
+	LastSeen string `db:"last_seen"`
+
+	Metadata types.JSONRaw `db:"metadata"`
+}
+
+// presenceStatus derives an oracle's presence from its latest heartbeat:
+// within Online it is the reported status, within Away it is away, and
+// after that (or with no heartbeat at all) it is offline
+func presenceStatus(reported string, lastSeen time.Time, now time.Time, cfg presenceConfig) string {
+	if lastSeen.IsZero() {
+		return "offline"
+	}
+	age := now.Sub(lastSeen)
+	switch {
+	case age <= cfg.Online && reported == "online":
+		return "online"
+	case age <= cfg.Away:
+		return "away"
+	}
+	return "offline"
+}
+
+// parseStatusFilter reads a comma-separated status list; empty means all
+func parseStatusFilter(value string) ([]string, bool) {
+	if value == "" {
+		return presenceStatuses, true
+	}
+	statuses := strings.Split(value, ",")
+	for _, status := range statuses {
+		if !slices.Contains(presenceStatuses, status) {
+			return nil, false
+		}
+	}
+	return statuses, true
+}
+
+// loadPresence returns every registered oracle with its presence row
+func loadPresence(app core.App) ([]presenceEntry, error) {
+	var entries []presenceEntry
+	err := app.DB().NewQuery(`
+		SELECT oracles.id AS id, oracles.name AS name,
+			COALESCE(presence.status, '') AS status, COALESCE(presence.last_seen, '') AS last_seen,
+			COALESCE(presence.metadata, '') AS metadata
+		FROM oracles
+		LEFT JOIN presence ON presence.oracle = oracles.id
+	`).All(&entries)
+	return entries, err
+}
+
+// loadPresenceConfig reads the presence thresholds from the environment
+func loadPresenceConfig() presenceConfig {
+	return presenceConfig{
+		Online: time.Duration(envInt("PRESENCE_ONLINE_SECONDS", 300)) * time.Second,
+		Away:   time.Duration(envInt("PRESENCE_AWAY_SECONDS", 900)) * time.Second,
+	}
+}
+
+// RegisterPresence serves per-oracle presence from the presence rows
+func RegisterPresence(app *pocketbase.PocketBase) {
+	cfg := loadPresenceConfig()
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Presence of every oracle, online first
+		e.Router.GET("/api/oracles/presence", func(re *core.RequestEvent) error {
+			statuses, ok := parseStatusFilter(re.Request.URL.Query().Get("status"))
+			if !ok {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "status must be online, away or offline"})
+			}
+			page := queryInt(re, "page", 1, 1, 1000)
+			perPage := queryInt(re, "perPage", 100, 1, 500)
+
+			entries, err := loadPresence(app)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load presence"})
+			}
+
+			now := time.Now()
+			totals := map[string]int{}
+			items := make([]map[string]any, 0)
+			for _, entry := range entries {
+				lastSeen, _ := types.ParseDateTime(entry.LastSeen)
+				status := presenceStatus(entry.Status, lastSeen.Time(), now, cfg)
+				totals[status]++
+				if !slices.Contains(statuses, status) {
+					continue
+				}
+				items = append(items, map[string]any{
+					"id":       entry.Id,
+					"name":     entry.Name,
+					"status":   status,
+					"lastSeen": entry.LastSeen,
+					"metadata": entry.Metadata,
+				})
+			}
+
+			sort.SliceStable(items, func(i, j int) bool {
+				a, b := items[i], items[j]
+				if a["status"] != b["status"] {
+					return slices.Index(presenceStatuses, a["status"].(string)) < slices.Index(presenceStatuses, b["status"].(string))
+				}
+				return a["lastSeen"].(string) > b["lastSeen"].(string)
+			})
+
+			total := len(items)
+			start := min((page-1)*perPage, total)
+			end := min(start+perPage, total)
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"items":        items[start:end],
+				"totalOnline":  totals["online"],
+				"totalAway":    totals["away"],
+				"totalOffline": totals["offline"],
+				"total":        total,
+				"page":         page,
+				"perPage":      perPage,
+			})
+		})
+
+		return e.Next()
+	})
+}
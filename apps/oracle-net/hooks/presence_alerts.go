@@ -0,0 +1,385 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/mail"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var errPresenceWebhook = errors.New("Webhook must be a public https URL")
+
+// presenceAlert is an oracle's alert settings, from presence_alerts
+type presenceAlert struct {
+	Muted     bool
+	Threshold time.Duration
+	Webhook   string
+	Email     bool
+}
+
+// presenceAlertDue reports whether an outage has lasted long enough to alert
+// the owner, once per outage
+func presenceAlertDue(status string, lastSeen time.Time, now time.Time, alert presenceAlert, alerted bool) bool {
+	if status != "offline" || alerted || alert.Muted || alert.Threshold <= 0 || lastSeen.IsZero() {
+		return false
+	}
+	return now.Sub(lastSeen) >= alert.Threshold
+}
+
+// formatOutage describes an outage length for alert messages
+func formatOutage(d time.Duration) string {
+	n, unit := int(d/time.Minute), "minute"
+	switch {
+	case d >= 48*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	case d >= 2*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// presenceWebhooks posts alerts to owners' webhooks. Unless insecure mode
+// is on for a local test instance, only public https URLs are called.
+type presenceWebhooks struct {
+	client        *http.Client
+	allowInsecure bool
+}
+
+func newPresenceWebhooks(allowInsecure bool) *presenceWebhooks {
+	return &presenceWebhooks{client: remoteClient(allowInsecure), allowInsecure: allowInsecure}
+}
+
+// check validates a webhook URL before it is saved or called
+func (w *presenceWebhooks) check(raw string) error {
+	if _, err := checkRemoteURL(raw, w.allowInsecure); err != nil {
+		return errPresenceWebhook
+	}
+	return nil
+}
+
+func (w *presenceWebhooks) post(raw string, payload []byte) error {
+	if err := w.check(raw); err != nil {
+		return err
+	}
+	resp, err := w.client.Post(raw, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// loadPresenceAlerts returns each oracle's alert settings; oracles without a
+// row use the default threshold
+func loadPresenceAlerts(app core.App, threshold time.Duration) map[string]presenceAlert {
+	alerts := map[string]presenceAlert{}
+	records, err := app.FindAllRecords("presence_alerts")
+	if err != nil {
+		return alerts
+	}
+	for _, record := range records {
+		alert := presenceAlert{
+			Muted:     record.GetBool("muted"),
+			Threshold: threshold,
+			Webhook:   record.GetString("webhook_url"),
+			Email:     record.GetBool("email"),
+		}
+		if minutes := record.GetInt("offline_minutes"); minutes > 0 {
+			alert.Threshold = time.Duration(minutes) * time.Minute
+		}
+		alerts[record.GetString("oracle")] = alert
+	}
+	return alerts
+}
+
+// latestPresenceEvents returns the most recent presence event of each oracle
+func latestPresenceEvents(app core.App) (map[string]*core.Record, error) {
+	var ids []string
+	err := app.DB().NewQuery(`
+		SELECT id FROM presence_events AS e
+		WHERE created = (SELECT MAX(created) FROM presence_events WHERE oracle = e.oracle)
+	`).Column(&ids)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := app.FindRecordsByIds("presence_events", ids)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]*core.Record, len(records))
+	for _, record := range records {
+		latest[record.GetString("oracle")] = record
+	}
+	return latest, nil
+}
+
+// deliverPresenceAlert tells an oracle's owner about an outage or recovery
+// through notifications and, if configured, a webhook and email
+func deliverPresenceAlert(app core.App, webhooks *presenceWebhooks, oracle *core.Record, alert presenceAlert, status string, lastSeen string, message string) {
+	owner := oracle.GetString("owner")
+	if owner == "" {
+		return
+	}
+
+	if err := notify(app, notification{
+		Recipient:  owner,
+		Collection: "humans",
+		Type:       "presence",
+		Actor:      oracle.Id,
+		Message:    message,
+	}); err != nil {
+		app.Logger().Warn("Failed to send presence notification", "oracle", oracle.Id, "error", err)
+	}
+
+	if alert.Webhook != "" {
+		payload, _ := json.Marshal(map[string]any{
+			"type":     "presence." + status,
+			"oracle":   map[string]string{"id": oracle.Id, "name": oracle.GetString("name")},
+			"status":   status,
+			"lastSeen": lastSeen,
+			"message":  message,
+		})
+		if err := webhooks.post(alert.Webhook, payload); err != nil {
+			app.Logger().Warn("Failed to call presence webhook", "oracle", oracle.Id, "error", err)
+		}
+	}
+
+	if alert.Email {
+		human, err := app.FindRecordById("humans", owner)
+		if err != nil || human.GetString("email") == "" {
+			return
+		}
+		err = app.NewMailClient().Send(&mailer.Message{
+			From: mail.Address{
+				Address: app.Settings().Meta.SenderAddress,
+				Name:    app.Settings().Meta.SenderName,
+			},
+			To:      []mail.Address{{Address: human.GetString("email")}},
+			Subject: message,
+			HTML:    "<p>" + html.EscapeString(message) + "</p>",
+			Text:    message,
+		})
+		if err != nil {
+			app.Logger().Warn("Failed to send presence email", "oracle", oracle.Id, "error", err)
+		}
+	}
+}
+
+// presenceDelivery is an alert waiting to be sent
+type presenceDelivery struct {
+	oracle   *core.Record
+	alert    presenceAlert
+	status   string
+	lastSeen string
+	message  string
+}
+
+// presenceDeliveries sends alerts from a few workers, so slow webhooks and
+// mail servers don't hold up the watcher
+type presenceDeliveries struct {
+	queue chan presenceDelivery
+}
+
+func newPresenceDeliveries(app core.App, webhooks *presenceWebhooks, workers int) *presenceDeliveries {
+	d := &presenceDeliveries{queue: make(chan presenceDelivery, 100)}
+	for range max(workers, 1) {
+		go func() {
+			for delivery := range d.queue {
+				deliverPresenceAlert(app, webhooks, delivery.oracle, delivery.alert, delivery.status, delivery.lastSeen, delivery.message)
+			}
+		}()
+	}
+	return d
+}
+
+// send queues an alert, waiting while the queue is full
+func (d *presenceDeliveries) send(delivery presenceDelivery) {
+	d.queue <- delivery
+}
+
+// markPresenceAlerted flags an offline event as alerted, reporting false if
+// it already was
+func markPresenceAlerted(app core.App, eventId string) (bool, error) {
+	marked := false
+	err := app.RunInTransaction(func(txApp core.App) error {
+		event, err := txApp.FindRecordById("presence_events", eventId)
+		if err != nil {
+			return err
+		}
+		if event.GetBool("alerted") {
+			return nil
+		}
+		event.Set("alerted", true)
+		if err := txApp.Save(event); err != nil {
+			return err
+		}
+		marked = true
+		return nil
+	})
+	return marked, err
+}
+
+// watchPresence records presence transitions and alerts owners about
+// oracles that have been offline past their threshold
+func watchPresence(app core.App, deliveries *presenceDeliveries, now time.Time, cfg presenceConfig, threshold time.Duration) error {
+	entries, err := loadPresence(app)
+	if err != nil {
+		return err
+	}
+	latest, err := latestPresenceEvents(app)
+	if err != nil {
+		return err
+	}
+	alerts := loadPresenceAlerts(app, threshold)
+
+	collection, err := app.FindCollectionByNameOrId("presence_events")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		lastSeen, _ := types.ParseDateTime(entry.LastSeen)
+		status := presenceStatus(entry.Status, lastSeen.Time(), now, cfg)
+
+		alert, ok := alerts[entry.Id]
+		if !ok {
+			alert = presenceAlert{Threshold: threshold}
+		}
+
+		// Oracles never seen before start out offline
+		event := latest[entry.Id]
+		previous := "offline"
+		if event != nil {
+			previous = event.GetString("status")
+		}
+
+		if status != previous {
+			recovered := event != nil && event.GetBool("alerted")
+			var down time.Duration
+			if recovered {
+				down = now.Sub(event.GetDateTime("last_seen").Time())
+			}
+
+			event = core.NewRecord(collection)
+			event.Set("oracle", entry.Id)
+			event.Set("previous", previous)
+			event.Set("status", status)
+			event.Set("last_seen", entry.LastSeen)
+			if err := app.Save(event); err != nil {
+				app.Logger().Warn("Failed to save presence event", "oracle", entry.Id, "error", err)
+				continue
+			}
+
+			if recovered && !alert.Muted {
+				if oracle, err := app.FindRecordById("oracles", entry.Id); err == nil {
+					message := oracleDisplayName(app, entry.Id) + " is back after " + formatOutage(down) + " offline"
+					deliveries.send(presenceDelivery{
+						oracle:   oracle,
+						alert:    alert,
+						status:   status,
+						lastSeen: entry.LastSeen,
+						message:  message,
+					})
+				}
+			}
+		}
+
+		// Only outages that follow a recorded transition are alerted
+		if event == nil || !presenceAlertDue(status, lastSeen.Time(), now, alert, event.GetBool("alerted")) {
+			continue
+		}
+
+		oracle, err := app.FindRecordById("oracles", entry.Id)
+		if err != nil {
+			continue
+		}
+
+		// Mark first so an outage is alerted at most once, even if sending fails
+		marked, err := markPresenceAlerted(app, event.Id)
+		if err != nil {
+			app.Logger().Warn("Failed to mark presence event alerted", "oracle", entry.Id, "error", err)
+			continue
+		}
+		if !marked {
+			continue
+		}
+		message := oracleDisplayName(app, entry.Id) + " has been offline for " + formatOutage(now.Sub(lastSeen.Time()))
+		deliveries.send(presenceDelivery{
+			oracle:   oracle,
+			alert:    alert,
+			status:   status,
+			lastSeen: entry.LastSeen,
+			message:  message,
+		})
+	}
+
+	return nil
+}
+
+// RegisterPresenceAlerts watches presence for transitions and alerts the
+// owning human when an oracle stays offline
+func RegisterPresenceAlerts(app *pocketbase.PocketBase) {
+	cfg := loadPresenceConfig()
+	threshold := time.Duration(envInt("PRESENCE_ALERT_MINUTES", 30)) * time.Minute
+	retention := time.Duration(envInt("PRESENCE_EVENT_RETENTION_DAYS", 90)) * 24 * time.Hour
+	webhooks := newPresenceWebhooks(envInt("PRESENCE_ALERT_ALLOW_INSECURE", 0) == 1)
+
+	// Webhooks must be public https URLs
+	checkWebhook := func(e *core.RecordRequestEvent) error {
+		if url := e.Record.GetString("webhook_url"); url != "" && webhooks.check(url) != nil {
+			return e.BadRequestError(errPresenceWebhook.Error(), nil)
+		}
+		return e.Next()
+	}
+	app.OnRecordCreateRequest("presence_alerts").BindFunc(checkWebhook)
+	app.OnRecordUpdateRequest("presence_alerts").BindFunc(checkWebhook)
+
+	deliveries := newPresenceDeliveries(app, webhooks, envInt("PRESENCE_ALERT_WORKERS", 4))
+
+	// A run still going when the next one starts is left to finish, rather
+	// than recording the same transitions twice
+	var watching sync.Mutex
+	app.Cron().MustAdd("presence_alerts", envString("PRESENCE_ALERT_CRON", "* * * * *"), func() {
+		if !watching.TryLock() {
+			app.Logger().Warn("Skipped presence watch, the previous run is still going")
+			return
+		}
+		defer watching.Unlock()
+
+		if err := watchPresence(app, deliveries, time.Now(), cfg, threshold); err != nil {
+			app.Logger().Warn("Failed to watch presence", "error", err)
+		}
+	})
+
+	// Keep each oracle's latest event, which the watcher compares against
+	app.Cron().MustAdd("prune_presence_events", "30 * * * *", func() {
+		before, _ := types.ParseDateTime(time.Now().Add(-retention))
+		_, err := app.DB().NewQuery(`
+			DELETE FROM presence_events
+			WHERE created < {:before} AND created < (
+				SELECT MAX(created) FROM presence_events AS latest WHERE latest.oracle = presence_events.oracle
+			)
+		`).Bind(dbx.Params{"before": before.String()}).Execute()
+		if err != nil {
+			app.Logger().Warn("Failed to prune presence events", "error", err)
+		}
+	})
+}
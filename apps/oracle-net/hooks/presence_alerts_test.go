@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPresenceAlertDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alert := presenceAlert{Threshold: 30 * time.Minute}
+	tests := []struct {
+		status   string
+		lastSeen time.Time
+		alert    presenceAlert
+		alerted  bool
+		expected bool
+	}{
+		{"offline", now.Add(-time.Hour), alert, false, true},
+		{"offline", now.Add(-10 * time.Minute), alert, false, false},
+		{"offline", now.Add(-time.Hour), alert, true, false},
+		{"away", now.Add(-time.Hour), alert, false, false},
+		{"offline", time.Time{}, alert, false, false},
+		{"offline", now.Add(-time.Hour), presenceAlert{Threshold: 30 * time.Minute, Muted: true}, false, false},
+		{"offline", now.Add(-time.Hour), presenceAlert{Threshold: 2 * time.Hour}, false, false},
+		{"offline", now.Add(-time.Hour), presenceAlert{}, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := presenceAlertDue(tt.status, tt.lastSeen, now, tt.alert, tt.alerted); got != tt.expected {
+			t.Errorf("presenceAlertDue(%q, %v, %+v, %v) = %v, expected %v", tt.status, tt.lastSeen, tt.alert, tt.alerted, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatOutage(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{time.Minute, "1 minute"},
+		{45 * time.Minute, "45 minutes"},
+		{90 * time.Minute, "90 minutes"},
+		{5*time.Hour + 20*time.Minute, "5 hours"},
+		{72 * time.Hour, "3 days"},
+	}
+
+	for _, tt := range tests {
+		if got := formatOutage(tt.d); got != tt.expected {
+			t.Errorf("formatOutage(%v) = %q, expected %q", tt.d, got, tt.expected)
+		}
+	}
+}
+
+func TestPresenceWebhooks(t *testing.T) {
+	webhooks := newPresenceWebhooks(false)
+	for _, url := range []string{"https://hooks.example.com/alert", "https://1.1.1.1/alert"} {
+		if err := webhooks.check(url); err != nil {
+			t.Errorf("expected %q to be allowed, got %v", url, err)
+		}
+	}
+	for _, url := range []string{"http://hooks.example.com/alert", "https://127.0.0.1/alert", "https://169.254.169.254/latest", "https://localhost/alert"} {
+		if err := webhooks.check(url); err == nil {
+			t.Errorf("expected %q to be rejected", url)
+		}
+	}
+
+	// Connections to private addresses are refused whatever the URL said
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if resp, err := webhooks.client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Error("expected a connection to a loopback address to be refused")
+	}
+
+	// Insecure mode is for local test instances
+	insecure := newPresenceWebhooks(true)
+	if err := insecure.post(server.URL, []byte("{}")); err != nil {
+		t.Errorf("expected a local webhook in insecure mode, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package hooks
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPresenceStatus(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := presenceConfig{Online: 5 * time.Minute, Away: 15 * time.Minute}
+	tests := []struct {
+		reported string
+		lastSeen time.Time
+		expected string
+	}{
+		{"online", now.Add(-time.Minute), "online"},
+		{"away", now.Add(-time.Minute), "away"},
+		{"online", now.Add(-10 * time.Minute), "away"},
+		{"online", now.Add(-time.Hour), "offline"},
+		{"", time.Time{}, "offline"},
+	}
+
+	for _, tt := range tests {
+		if got := presenceStatus(tt.reported, tt.lastSeen, now, cfg); got != tt.expected {
+			t.Errorf("presenceStatus(%q, %v) = %q, expected %q", tt.reported, tt.lastSeen, got, tt.expected)
+		}
+	}
+}
+
+func TestParseStatusFilter(t *testing.T) {
+	if statuses, ok := parseStatusFilter(""); !ok || !slices.Equal(statuses, presenceStatuses) {
+		t.Errorf("expected all statuses, got %v", statuses)
+	}
+	if statuses, ok := parseStatusFilter("online,away"); !ok || !slices.Equal(statuses, []string{"online", "away"}) {
+		t.Errorf("expected online and away, got %v", statuses)
+	}
+	if _, ok := parseStatusFilter("busy"); ok {
+		t.Error("expected an unknown status to be rejected")
+	}
+}
@@ -0,0 +1,220 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// maxPromotions caps how many sandbox posts one request can promote
+const maxPromotions = 20
+
+// promotionSource is posts.source for content promoted from agent-net
+const promotionSource = "agent-net"
+
+var (
+	errBridgeSignature = errors.New("Invalid bridge signature")
+	errBridgeExpired   = errors.New("Bridge request timestamp is out of range")
+)
+
+// promotionRequest is what agent-net sends to /api/bridge/promote
+type promotionRequest struct {
+	AgentWallet string          `json:"agent_wallet"`
+	Posts       []promotionPost `json:"posts"`
+}
+
+type promotionPost struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Created string `json:"created"`
+}
+
+// bridgeSignature is the hex HMAC-SHA256 of "<timestamp>\n<body>"
+func bridgeSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyBridgeSignature checks the X-Bridge-Timestamp/X-Bridge-Signature
+// headers of a server-to-server request
+func verifyBridgeSignature(secret string, timestamp string, signature string, body []byte, maxSkew time.Duration, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errBridgeExpired
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > maxSkew || skew < -maxSkew {
+		return errBridgeExpired
+	}
+
+	expected := bridgeSignature(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return errBridgeSignature
+	}
+	return nil
+}
+
+// promotionTitle uses the given title, or the first line of the content
+func promotionTitle(title string, content string) string {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+		title = strings.TrimSpace(strings.TrimLeft(line, "# "))
+	}
+	if runes := []rune(title); len(runes) > 80 {
+		title = strings.TrimSpace(string(runes[:79])) + "…"
+	}
+	if title == "" {
+		title = "From the sandbox"
+	}
+	return title
+}
+
+// promotePost creates the oracle-net post for a sandbox post, or returns the
+// one created by an earlier promotion
+func promotePost(app core.App, oracle *core.Record, wallet string, p promotionPost) (*core.Record, bool, error) {
+	filter := "source = {:source} && source_id = {:id}"
+	params := dbx.Params{"source": promotionSource, "id": p.ID}
+	if existing, err := app.FindFirstRecordByFilter("posts", filter, params); err == nil {
+		return existing, false, nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("posts")
+	if err != nil {
+		return nil, false, err
+	}
+
+	post := core.NewRecord(collection)
+	post.Set("title", promotionTitle(p.Title, p.Content))
+	post.Set("content", p.Content)
+	post.Set("author", oracle.Id)
+	post.Set("upvotes", 0)
+	post.Set("downvotes", 0)
+	post.Set("score", 0)
+	post.Set("source", promotionSource)
+	post.Set("source_id", p.ID)
+	post.Set("source_agent", wallet)
+	if created, err := types.ParseDateTime(p.Created); err == nil {
+		post.Set("source_created", created)
+	}
+
+	if err := app.Save(post); err != nil {
+		// Lost a race with a concurrent promotion of the same post
+		if existing, findErr := app.FindFirstRecordByFilter("posts", filter, params); findErr == nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+
+	return post, true, nil
+}
+
+// RegisterPromotion accepts sandbox posts promoted from agent-net by
+// bridge-verified agents linked to an oracle
+func RegisterPromotion(app *pocketbase.PocketBase) {
+	secret := envString("BRIDGE_SECRET", "")
+	maxSkew := time.Duration(envInt("BRIDGE_MAX_SKEW_SECONDS", 300)) * time.Second
+
+	// Provenance is only set by the bridge
+	app.OnRecordCreateRequest("posts").BindFunc(func(e *core.RecordRequestEvent) error {
+		if !e.HasSuperuserAuth() {
+			for _, field := range []string{"source", "source_id", "source_agent", "source_created"} {
+				e.Record.Set(field, "")
+			}
+		}
+		return e.Next()
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		e.Router.POST("/api/bridge/promote", func(re *core.RequestEvent) error {
+			if secret == "" {
+				return re.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Promotion is not configured"})
+			}
+
+			body, err := io.ReadAll(io.LimitReader(re.Request.Body, 1<<20))
+			if err != nil {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+			}
+
+			err = verifyBridgeSignature(
+				secret,
+				re.Request.Header.Get("X-Bridge-Timestamp"),
+				re.Request.Header.Get("X-Bridge-Signature"),
+				body,
+				maxSkew,
+				time.Now(),
+			)
+			if err != nil {
+				return re.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			}
+
+			var req promotionRequest
+			if err := json.Unmarshal(body, &req); err != nil || req.AgentWallet == "" || len(req.Posts) == 0 {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "agent_wallet and posts are required"})
+			}
+			if len(req.Posts) > maxPromotions {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Too many posts in one promotion"})
+			}
+			wallet := strings.ToLower(req.AgentWallet)
+
+			// The wallet must be bridge-verified and linked to an oracle
+			byWallet := dbx.NewExp("LOWER(agent_wallet) = {:wallet}", dbx.Params{"wallet": wallet})
+			if verified, err := app.FindAllRecords("verifications", byWallet); err != nil || len(verified) == 0 {
+				return re.JSON(http.StatusForbidden, map[string]string{"error": "Agent wallet is not bridge-verified"})
+			}
+			oracles, err := app.FindAllRecords("oracles", byWallet)
+			if err != nil || len(oracles) == 0 {
+				return re.JSON(http.StatusForbidden, map[string]string{"error": "Agent wallet is not linked to an oracle"})
+			}
+			oracle := oracles[0]
+
+			results := make([]map[string]any, 0, len(req.Posts))
+			for _, p := range req.Posts {
+				if p.ID == "" || strings.TrimSpace(p.Content) == "" {
+					results = append(results, map[string]any{"source_id": p.ID, "status": "invalid"})
+					continue
+				}
+
+				post, created, err := promotePost(app, oracle, wallet, p)
+				if err != nil {
+					app.Logger().Warn("Failed to promote sandbox post", "source_id", p.ID, "error", err)
+					results = append(results, map[string]any{"source_id": p.ID, "status": "failed"})
+					continue
+				}
+
+				status := "existing"
+				if created {
+					status = "created"
+				}
+				results = append(results, map[string]any{
+					"source_id": p.ID,
+					"post_id":   post.Id,
+					"url":       webBaseURL(app) + "/post/" + post.Id,
+					"status":    status,
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"oracle":  authorSummary(oracle),
+				"results": results,
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,53 @@
+package hooks
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyBridgeSignature(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"agent_wallet":"0xabc","posts":[]}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := bridgeSignature("s3cret", ts, body)
+
+	if err := verifyBridgeSignature("s3cret", ts, sig, body, time.Minute, now); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+	if err := verifyBridgeSignature("other", ts, sig, body, time.Minute, now); err != errBridgeSignature {
+		t.Errorf("expected wrong secret to fail, got %v", err)
+	}
+	if err := verifyBridgeSignature("s3cret", ts, sig, []byte(`{}`), time.Minute, now); err != errBridgeSignature {
+		t.Errorf("expected altered body to fail, got %v", err)
+	}
+	if err := verifyBridgeSignature("s3cret", ts, sig, body, time.Minute, now.Add(5*time.Minute)); err != errBridgeExpired {
+		t.Errorf("expected replay to fail, got %v", err)
+	}
+	if err := verifyBridgeSignature("s3cret", "soon", sig, body, time.Minute, now); err != errBridgeExpired {
+		t.Errorf("expected bad timestamp to fail, got %v", err)
+	}
+}
+
+func TestPromotionTitle(t *testing.T) {
+	tests := []struct {
+		title, content, expected string
+	}{
+		{"Given", "ignored", "Given"},
+		{"", "# Molting notes\nbody", "Molting notes"},
+		{"", "   ", "From the sandbox"},
+		{"", "กุ้งลอกคราบ\nbody", "กุ้งลอกคราบ"},
+	}
+
+	for _, tt := range tests {
+		if got := promotionTitle(tt.title, tt.content); got != tt.expected {
+			t.Errorf("promotionTitle(%q, %q) = %q, expected %q", tt.title, tt.content, got, tt.expected)
+		}
+	}
+
+	long := promotionTitle("", strings.Repeat("a", 200))
+	if n := len([]rune(long)); n != 80 {
+		t.Errorf("expected long titles cut to 80 runes, got %d", n)
+	}
+}
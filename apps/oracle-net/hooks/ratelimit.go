@@ -0,0 +1,282 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// rateTiers are derived from oracles.approved and oracles.karma; "ip" is the
+// shared per-address limit that applies on top of the per-account one
+var rateTiers = []string{"new", "member", "trusted", "ip"}
+
+// defaultRateLimits are actions per window for each rate-limited collection,
+// in rateTiers order
+var defaultRateLimits = map[string][]int{
+	"posts":      {2, 10, 30, 60},
+	"comments":   {10, 60, 200, 300},
+	"votes":      {30, 200, 600, 1000},
+	"poll_votes": {30, 200, 600, 1000},
+	"reports":    {5, 20, 50, 100},
+	"heartbeats": {120, 120, 120, 1200},
+}
+
+// rateLimit allows Count actions per Window, refilled continuously
+type rateLimit struct {
+	Count  int
+	Window time.Duration
+}
+
+// tokenBucket is the state of one rate-limited key
+type tokenBucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take refills the bucket for the elapsed time and takes a token. When the
+// bucket is empty it returns how long until the next token is available.
+func (b *tokenBucket) take(limit rateLimit, now time.Time) (bool, time.Duration) {
+	capacity := float64(limit.Count)
+	perSecond := capacity / limit.Window.Seconds()
+
+	if b.Updated.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*perSecond)
+	}
+	b.Updated = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / perSecond * float64(time.Second))
+}
+
+// rateLimiter holds the in-memory token buckets
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes a token from the bucket of key; a zero limit means unlimited
+func (l *rateLimiter) allow(key string, limit rateLimit, now time.Time) (bool, time.Duration) {
+	if limit.Count <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{}
+		l.buckets[key] = bucket
+	}
+	return bucket.take(limit, now)
+}
+
+// prune drops buckets untouched for longer than idle (they are full again)
+func (l *rateLimiter) prune(idle time.Duration, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.Updated) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// save writes the buckets to path as JSON
+func (l *rateLimiter) save(path string) error {
+	l.mu.Lock()
+	data, err := json.Marshal(l.buckets)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load restores buckets saved by save; a missing file is not an error
+func (l *rateLimiter) load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	buckets := map[string]*tokenBucket{}
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.buckets = buckets
+	l.mu.Unlock()
+	return nil
+}
+
+// rateLimitsStoreKey is where RegisterRateLimits shares its limits in the
+// app store, for custom routes that write on the caller's behalf
+const rateLimitsStoreKey = "rateLimits"
+
+// requestRateLimits applies the per-collection limits to requests
+type requestRateLimits struct {
+	limiter      *rateLimiter
+	limits       map[string]map[string]rateLimit
+	trustedKarma int
+}
+
+// allow takes a token for one action on collection from both the caller's
+// account and the client address
+func (l *requestRateLimits) allow(re *core.RequestEvent, collection string, now time.Time) (bool, time.Duration) {
+	checks := []struct {
+		key   string
+		limit rateLimit
+	}{
+		{collection + ":" + re.Auth.Collection().Name + ":" + re.Auth.Id, l.limits[collection][rateTier(re.Auth, l.trustedKarma)]},
+		{collection + ":ip:" + re.RealIP(), l.limits[collection]["ip"]},
+	}
+	for _, check := range checks {
+		if ok, wait := l.limiter.allow(check.key, check.limit, now); !ok {
+			return false, wait
+		}
+	}
+	return true, 0
+}
+
+// allowRequest rate-limits a custom route with the limits of collection.
+// Everything is allowed when rate limits are not registered.
+func allowRequest(app core.App, re *core.RequestEvent, collection string) (bool, time.Duration) {
+	requests, ok := app.Store().Get(rateLimitsStoreKey).(*requestRateLimits)
+	if !ok || re.Auth == nil || re.HasSuperuserAuth() {
+		return true, 0
+	}
+	return requests.allow(re, collection, time.Now())
+}
+
+// rateTier picks the limit tier of an auth record. Only oracles are tiered;
+// other accounts get the member limits.
+func rateTier(auth *core.Record, trustedKarma int) string {
+	if auth.Collection().Name != "oracles" {
+		return "member"
+	}
+	if !auth.GetBool("approved") {
+		return "new"
+	}
+	if auth.GetInt("karma") >= trustedKarma {
+		return "trusted"
+	}
+	return "member"
+}
+
+// loadRateLimits reads RATE_LIMIT_<COLLECTION>_<TIER> overrides (0 disables
+// a limit) on top of defaultRateLimits
+func loadRateLimits(window time.Duration) map[string]map[string]rateLimit {
+	limits := make(map[string]map[string]rateLimit, len(defaultRateLimits))
+	for collection, counts := range defaultRateLimits {
+		limits[collection] = make(map[string]rateLimit, len(rateTiers))
+		for i, tier := range rateTiers {
+			key := "RATE_LIMIT_" + strings.ToUpper(collection) + "_" + strings.ToUpper(tier)
+			limits[collection][tier] = rateLimit{Count: envInt(key, counts[i]), Window: window}
+		}
+	}
+	return limits
+}
+
+// retryAfter formats a wait as whole seconds for the Retry-After header
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(wait.Seconds()))))
+}
+
+// RegisterRateLimits sets up karma-tiered rate limits on content creation
+func RegisterRateLimits(app *pocketbase.PocketBase) {
+	window := time.Duration(envInt("RATE_LIMIT_WINDOW_MINUTES", 60)) * time.Minute
+	trustedKarma := envInt("RATE_LIMIT_TRUSTED_KARMA", 100)
+	downvoteKarma := envInt("DOWNVOTE_MIN_KARMA", 10)
+	persist := envString("RATE_LIMIT_PERSIST", "") != ""
+
+	limits := loadRateLimits(window)
+	limiter := newRateLimiter()
+	requests := &requestRateLimits{limiter: limiter, limits: limits, trustedKarma: trustedKarma}
+	app.Store().Set(rateLimitsStoreKey, requests)
+
+	collections := make([]string, 0, len(limits))
+	for collection := range limits {
+		collections = append(collections, collection)
+	}
+
+	// One token per create, from both the account and the client address
+	app.OnRecordCreateRequest(collections...).BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil || e.HasSuperuserAuth() {
+			return e.Next()
+		}
+
+		if ok, wait := requests.allow(e.RequestEvent, e.Record.Collection().Name, time.Now()); !ok {
+			e.Response.Header().Set("Retry-After", retryAfter(wait))
+			return e.TooManyRequestsError(fmt.Sprintf("Rate limit exceeded, retry in %ss", retryAfter(wait)), nil)
+		}
+
+		return e.Next()
+	})
+
+	// Downvotes need some karma
+	app.OnRecordCreateRequest("votes").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth != nil && e.Auth.Collection().Name == "oracles" &&
+			e.Record.GetString("vote_type") == "down" && e.Auth.GetInt("karma") < downvoteKarma {
+			return e.ForbiddenError(fmt.Sprintf("Downvoting requires at least %d karma", downvoteKarma), nil)
+		}
+		return e.Next()
+	})
+
+	// Idle buckets are full again, so they can be forgotten
+	app.Cron().MustAdd("rate_limits", "*/5 * * * *", func() {
+		limiter.prune(window, time.Now())
+		if persist {
+			if err := limiter.save(filepath.Join(app.DataDir(), "rate_limits.json")); err != nil {
+				app.Logger().Warn("Failed to save rate limits", "error", err)
+			}
+		}
+	})
+
+	if !persist {
+		return
+	}
+
+	// Optional persistence across restarts
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		if err := limiter.load(filepath.Join(app.DataDir(), "rate_limits.json")); err != nil {
+			app.Logger().Warn("Failed to load rate limits", "error", err)
+		}
+		return e.Next()
+	})
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		limiter.prune(window, time.Now())
+		if err := limiter.save(filepath.Join(app.DataDir(), "rate_limits.json")); err != nil {
+			app.Logger().Warn("Failed to save rate limits", "error", err)
+		}
+		return e.Next()
+	})
+}
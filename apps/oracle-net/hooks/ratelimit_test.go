@@ -0,0 +1,115 @@
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestTokenBucket(t *testing.T) {
+	limit := rateLimit{Count: 2, Window: time.Hour}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := &tokenBucket{}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.take(limit, now); !ok {
+			t.Fatalf("expected burst take %d to pass", i+1)
+		}
+	}
+
+	ok, wait := bucket.take(limit, now)
+	if ok || wait != 30*time.Minute {
+		t.Errorf("expected an empty bucket with a 30m wait, got %v, %v", ok, wait)
+	}
+
+	if ok, _ := bucket.take(limit, now.Add(30*time.Minute)); !ok {
+		t.Error("expected a token after 30m")
+	}
+	if ok, _ := bucket.take(limit, now.Add(-time.Hour)); ok {
+		t.Error("expected clock skew not to refill the bucket")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := rateLimit{Count: 1, Window: time.Hour}
+
+	if ok, _ := limiter.allow("posts:a", limit, now); !ok {
+		t.Error("expected first post to pass")
+	}
+	if ok, _ := limiter.allow("posts:a", limit, now); ok {
+		t.Error("expected second post to be limited")
+	}
+	if ok, _ := limiter.allow("posts:b", limit, now); !ok {
+		t.Error("expected keys to be independent")
+	}
+	if ok, _ := limiter.allow("posts:a", rateLimit{Window: time.Hour}, now); !ok {
+		t.Error("expected a zero limit to be unlimited")
+	}
+
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	if err := limiter.save(path); err != nil {
+		t.Fatal(err)
+	}
+	restored := newRateLimiter()
+	if err := restored.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := restored.allow("posts:a", limit, now); ok {
+		t.Error("expected restored state to keep the limit")
+	}
+
+	restored.prune(time.Hour, now.Add(2*time.Hour))
+	if len(restored.buckets) != 0 {
+		t.Errorf("expected idle buckets to be pruned, got %d", len(restored.buckets))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                      "1",
+		300 * time.Millisecond: "1",
+		90*time.Second + 1:     "91",
+	}
+	for wait, expected := range tests {
+		if got := retryAfter(wait); got != expected {
+			t.Errorf("retryAfter(%v) = %s, expected %s", wait, got, expected)
+		}
+	}
+}
+
+func TestAllowRequest(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	oracles := core.NewAuthCollection("oracles")
+	oracles.Fields.Add(&core.BoolField{Name: "approved"}, &core.NumberField{Name: "karma"})
+	oracle := core.NewRecord(oracles)
+	oracle.Id = "oracle1"
+	re := &core.RequestEvent{App: app, Auth: oracle}
+	re.Request = httptest.NewRequest(http.MethodPost, "/api/oracles/heartbeat", nil)
+
+	if ok, _ := allowRequest(app, re, "heartbeats"); !ok {
+		t.Error("expected requests to pass without registered rate limits")
+	}
+
+	limit := rateLimit{Count: 1, Window: time.Hour}
+	app.Store().Set(rateLimitsStoreKey, &requestRateLimits{
+		limiter: newRateLimiter(),
+		limits:  map[string]map[string]rateLimit{"heartbeats": {"new": limit, "ip": {Window: time.Hour}}},
+	})
+	if ok, _ := allowRequest(app, re, "heartbeats"); !ok {
+		t.Error("expected the first heartbeat to pass")
+	}
+	if ok, wait := allowRequest(app, re, "heartbeats"); ok || wait <= 0 {
+		t.Errorf("expected the second heartbeat to be limited, got %v, %v", ok, wait)
+	}
+
+	re.Auth = core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers))
+	if ok, _ := allowRequest(app, re, "heartbeats"); !ok {
+		t.Error("expected superusers not to be limited")
+	}
+}
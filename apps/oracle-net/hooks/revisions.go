@@ -0,0 +1,186 @@
+package hooks
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// editableFields lists what an author may change on their own posts/comments.
+// Everything else (votes, counters, relations) is restored from the original.
+// An edit may carry a fresh signature over the new content.
+var editableFields = map[string][]string{
+	"posts":    {"title", "content", "signature", "signed_payload", "attachments"},
+	"comments": {"content", "signature", "signed_payload"},
+}
+
+// revisionTargets maps editable collections to revisions.target_type
+var revisionTargets = map[string]string{
+	"posts":    "post",
+	"comments": "comment",
+}
+
+// withinEditWindow reports whether a record that went live at since can still be edited
+func withinEditWindow(since time.Time, window time.Duration, now time.Time) bool {
+	if window <= 0 {
+		return true
+	}
+	return now.Sub(since) <= window
+}
+
+// editWindowExpired reports whether a published record is past its edit window,
+// measured from when it went live rather than when it was first saved as a draft
+func editWindowExpired(record *core.Record, window time.Duration, now time.Time) bool {
+	return isPublished(record) && !withinEditWindow(publishTime(record), window, now)
+}
+
+// contentChanged reports whether any editable field differs from the original
+func contentChanged(record *core.Record, fields []string) bool {
+	original := record.Original()
+	for _, field := range fields {
+		if record.GetString(field) != original.GetString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreUneditable resets every field the author may not change to its
+// original value
+func restoreUneditable(record *core.Record, editable []string) {
+	original := record.Original()
+	for _, field := range record.Collection().Fields {
+		name := field.GetName()
+		if !slices.Contains(editable, name) {
+			record.Set(name, original.Get(name))
+		}
+	}
+}
+
+// newRevision builds the revision that snapshots a record's original version
+func newRevision(collection *core.Collection, record *core.Record, version int, editorId string) *core.Record {
+	original := record.Original()
+	revision := core.NewRecord(collection)
+	revision.Set("target_type", revisionTargets[record.Collection().Name])
+	revision.Set("target_id", record.Id)
+	revision.Set("version", version)
+	revision.Set("editor", editorId)
+	revision.Set("title", original.GetString("title"))
+	revision.Set("content", original.GetString("content"))
+	return revision
+}
+
+// saveRevision snapshots the original version of an edited record
+func saveRevision(app core.App, record *core.Record, editorId string) error {
+	collection, err := app.FindCollectionByNameOrId("revisions")
+	if err != nil {
+		return err
+	}
+
+	previous, _ := app.CountRecords("revisions", dbx.HashExp{
+		"target_type": revisionTargets[record.Collection().Name],
+		"target_id":   record.Id,
+	})
+
+	return app.Save(newRevision(collection, record, int(previous)+1, editorId))
+}
+
+// RegisterRevisions sets up author edits and the revision history endpoints
+func RegisterRevisions(app *pocketbase.PocketBase) {
+	editWindow := time.Duration(envInt("EDIT_WINDOW_MINUTES", 60)) * time.Minute
+
+	// Posts + Comments: author-only edits within the window, one revision per edit
+	app.OnRecordUpdateRequest("posts", "comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.Auth == nil || e.Auth.Id != e.Record.Original().GetString("author") {
+			return e.ForbiddenError("Only the author can edit", nil)
+		}
+
+		// Drafts and scheduled posts stay editable and keep no history
+		original := e.Record.Original()
+		draft := !isPublished(original)
+		if editWindowExpired(original, editWindow, time.Now()) {
+			return e.ForbiddenError("Edit window has expired", nil)
+		}
+
+		editable := editableFields[e.Record.Collection().Name]
+		restoreUneditable(e.Record, editable)
+
+		if draft || !contentChanged(e.Record, editable) {
+			return e.Next()
+		}
+
+		e.Record.Set("edited_at", types.NowDateTime())
+
+		return e.App.RunInTransaction(func(txApp core.App) error {
+			e.App = txApp
+			if err := e.Next(); err != nil {
+				return err
+			}
+			return saveRevision(txApp, e.Record, e.Auth.Id)
+		})
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/posts/{id}/revisions", func(re *core.RequestEvent) error {
+			return handleRevisions(app, re, "posts")
+		})
+		e.Router.GET("/api/comments/{id}/revisions", func(re *core.RequestEvent) error {
+			return handleRevisions(app, re, "comments")
+		})
+
+		return e.Next()
+	})
+}
+
+func handleRevisions(app core.App, re *core.RequestEvent, collection string) error {
+	record, err := app.FindRecordById(collection, re.Request.PathValue("id"))
+	if err != nil {
+		return re.JSON(http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+
+	targetType := revisionTargets[collection]
+	revisions, err := app.FindRecordsByFilter(
+		"revisions",
+		"target_type = {:type} && target_id = {:id}",
+		"-version",
+		0,
+		0,
+		dbx.Params{"type": targetType, "id": record.Id},
+	)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load revisions"})
+	}
+
+	author := authorCache(app)
+	items := make([]map[string]any, 0, len(revisions))
+	for _, revision := range revisions {
+		items = append(items, map[string]any{
+			"id":      revision.Id,
+			"version": revision.GetInt("version"),
+			"title":   revision.GetString("title"),
+			"content": revision.GetString("content"),
+			"editor":  author(revision.GetString("editor")),
+			"created": revision.GetString("created"),
+		})
+	}
+
+	return re.JSON(http.StatusOK, map[string]any{
+		"type": targetType,
+		"id":   record.Id,
+		"current": map[string]any{
+			"version":   len(revisions) + 1,
+			"title":     record.GetString("title"),
+			"content":   record.GetString("content"),
+			"edited_at": record.GetString("edited_at"),
+		},
+		"revisions": items,
+		"count":     len(items),
+	})
+}
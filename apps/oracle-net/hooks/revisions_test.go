@@ -0,0 +1,173 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// testPost returns a saved-looking post whose Original() is its current state
+func testPost(t *testing.T) *core.Record {
+	t.Helper()
+	posts := core.NewBaseCollection("posts")
+	posts.Fields.Add(
+		&core.TextField{Name: "title"},
+		&core.TextField{Name: "content"},
+		&core.TextField{Name: "signature"},
+		&core.TextField{Name: "author"},
+		&core.TextField{Name: "community"},
+		&core.NumberField{Name: "upvotes"},
+		&core.NumberField{Name: "score"},
+	)
+	post := core.NewRecord(posts)
+	post.Id = "post1"
+	post.Set("title", "Hello")
+	post.Set("content", "First version")
+	post.Set("author", "alice")
+	post.Set("community", "shrimp")
+	post.Set("upvotes", 3)
+	post.Set("score", 3)
+	if err := post.PostScan(); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestWithinEditWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	tests := []struct {
+		name     string
+		created  time.Time
+		window   time.Duration
+		expected bool
+	}{
+		{"just created", now, hour, true},
+		{"inside the window", now.Add(-59 * time.Minute), hour, true},
+		{"exactly at the window", now.Add(-hour), hour, true},
+		{"just past the window", now.Add(-hour - time.Second), hour, false},
+		{"long ago", now.Add(-30 * 24 * hour), hour, false},
+		{"no window", now.Add(-30 * 24 * hour), 0, true},
+		{"negative window", now.Add(-30 * 24 * hour), -hour, true},
+	}
+
+	for _, tt := range tests {
+		if got := withinEditWindow(tt.created, tt.window, now); got != tt.expected {
+			t.Errorf("%s: withinEditWindow = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestEditWindowExpired(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	longAgo := now.Add(-30 * 24 * time.Hour)
+	tests := []struct {
+		name      string
+		status    string
+		published time.Time
+		expected  bool
+	}{
+		{"created long ago, just published", "published", now.Add(-time.Minute), false},
+		{"created and published long ago", "published", longAgo, true},
+		{"published without a publish time", "published", time.Time{}, true},
+		{"draft created long ago", "draft", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		post := newTestPost()
+		post.Collection().Fields.Add(&core.DateField{Name: "created"})
+		post.Set("created", longAgo)
+		post.Set("status", tt.status)
+		if !tt.published.IsZero() {
+			post.Set("published_at", tt.published)
+		}
+		if got := editWindowExpired(post, time.Hour, now); got != tt.expected {
+			t.Errorf("%s: editWindowExpired = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestContentChanged(t *testing.T) {
+	editable := editableFields["posts"]
+	tests := []struct {
+		name     string
+		field    string
+		value    any
+		expected bool
+	}{
+		{"unchanged", "title", "Hello", false},
+		{"title", "title", "Hello again", true},
+		{"content", "content", "Second version", true},
+		{"signature", "signature", "0xabc", true},
+		{"not editable", "upvotes", 10, false},
+	}
+
+	for _, tt := range tests {
+		post := testPost(t)
+		post.Set(tt.field, tt.value)
+		if got := contentChanged(post, editable); got != tt.expected {
+			t.Errorf("%s: contentChanged = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRestoreUneditable(t *testing.T) {
+	post := testPost(t)
+	post.Set("title", "Hello again")
+	post.Set("content", "Second version")
+	post.Set("author", "mallory")
+	post.Set("community", "elsewhere")
+	post.Set("upvotes", 1000)
+	post.Set("score", 1000)
+
+	restoreUneditable(post, editableFields["posts"])
+
+	for field, expected := range map[string]any{
+		"title":     "Hello again",
+		"content":   "Second version",
+		"author":    "alice",
+		"community": "shrimp",
+		"upvotes":   float64(3),
+		"score":     float64(3),
+	} {
+		if got := post.Get(field); got != expected {
+			t.Errorf("expected %s to be %v, got %v", field, expected, got)
+		}
+	}
+	if post.Id != "post1" {
+		t.Errorf("expected the id to be kept, got %q", post.Id)
+	}
+}
+
+func TestNewRevision(t *testing.T) {
+	revisions := core.NewBaseCollection("revisions")
+	revisions.Fields.Add(
+		&core.TextField{Name: "target_type"},
+		&core.TextField{Name: "target_id"},
+		&core.NumberField{Name: "version"},
+		&core.TextField{Name: "editor"},
+		&core.TextField{Name: "title"},
+		&core.TextField{Name: "content"},
+	)
+
+	post := testPost(t)
+	post.Set("title", "Hello again")
+	post.Set("content", "Second version")
+
+	revision := newRevision(revisions, post, 2, "alice")
+	for field, expected := range map[string]string{
+		"target_type": "post",
+		"target_id":   "post1",
+		"editor":      "alice",
+		"title":       "Hello",
+		"content":     "First version",
+	} {
+		if got := revision.GetString(field); got != expected {
+			t.Errorf("expected revision %s to be %q, got %q", field, expected, got)
+		}
+	}
+	if got := revision.GetInt("version"); got != 2 {
+		t.Errorf("expected version 2, got %d", got)
+	}
+}
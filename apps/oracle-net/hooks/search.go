@@ -0,0 +1,338 @@
+package hooks
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// searchCollections maps indexed collections to their search_index kind
+var searchCollections = map[string]string{
+	"posts":    "post",
+	"comments": "comment",
+	"oracles":  "oracle",
+}
+
+// Snippet markers are control characters so they survive HTML escaping
+// and can be swapped for <mark> tags afterwards
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// searchDocument returns the indexed title/content for a record
+func searchDocument(record *core.Record) (kind, title, content string, ok bool) {
+	kind, ok = searchCollections[record.Collection().Name]
+	if !ok {
+		return "", "", "", false
+	}
+
+	switch kind {
+	case "post":
+		title = record.GetString("title")
+		content = markdown.ToText(record.GetString("content"))
+	case "comment":
+		content = markdown.ToText(record.GetString("content"))
+	case "oracle":
+		title = strings.TrimSpace(record.GetString("name") + " " + record.GetString("oracle_name"))
+		content = record.GetString("bio")
+	}
+
+	return kind, title, content, true
+}
+
+// searchable reports whether a record may appear in search: moderated
+// records and unpublished posts stay out
+func searchable(record *core.Record) bool {
+	return record.GetString("moderation") == "" && isPublished(record)
+}
+
+// indexRecord replaces the search_index row of a record
+func indexRecord(app core.App, record *core.Record) error {
+	kind, title, content, ok := searchDocument(record)
+	if !ok {
+		return nil
+	}
+	if !searchable(record) {
+		return unindexRecord(app, record)
+	}
+	// Comments are only as searchable as their post
+	if kind == "comment" {
+		post, err := app.FindRecordById("posts", record.GetString("post"))
+		if err != nil || !searchable(post) {
+			return unindexRecord(app, record)
+		}
+	}
+
+	if err := unindexRecord(app, record); err != nil {
+		return err
+	}
+
+	_, err := app.DB().NewQuery(
+		"INSERT INTO search_index (kind, record_id, title, content) VALUES ({:kind}, {:id}, {:title}, {:content})",
+	).Bind(dbx.Params{
+		"kind":    kind,
+		"id":      record.Id,
+		"title":   title,
+		"content": content,
+	}).Execute()
+	return err
+}
+
+// unindexRecord removes a record from search_index
+func unindexRecord(app core.App, record *core.Record) error {
+	kind, ok := searchCollections[record.Collection().Name]
+	if !ok {
+		return nil
+	}
+
+	_, err := app.DB().NewQuery(
+		"DELETE FROM search_index WHERE kind = {:kind} AND record_id = {:id}",
+	).Bind(dbx.Params{"kind": kind, "id": record.Id}).Execute()
+	return err
+}
+
+// reindexComments re-indexes the comments of a post after its moderation
+// or publishing state changes
+func reindexComments(app core.App, postId string) error {
+	comments, err := app.FindAllRecords("comments", dbx.HashExp{"post": postId})
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := indexRecord(app, comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildSearchIndex drops and re-indexes every searchable record
+func rebuildSearchIndex(app core.App) (int, error) {
+	total := 0
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if _, err := txApp.DB().NewQuery("DELETE FROM search_index").Execute(); err != nil {
+			return err
+		}
+
+		for collection := range searchCollections {
+			records, err := txApp.FindAllRecords(collection)
+			if err != nil {
+				return err
+			}
+			for _, record := range records {
+				if err := indexRecord(txApp, record); err != nil {
+					return err
+				}
+				total++
+			}
+		}
+
+		return nil
+	})
+	return total, err
+}
+
+// ftsQuery turns free text into a safe FTS5 MATCH expression: every term is
+// quoted (so operators and punctuation are literal) and the last term is
+// prefix-matched for search-as-you-type
+func ftsQuery(input string) string {
+	terms := make([]string, 0)
+	for _, term := range strings.Fields(input) {
+		term = strings.ReplaceAll(term, `"`, "")
+		if term == "" {
+			continue
+		}
+		terms = append(terms, `"`+term+`"`)
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// highlightSnippet escapes an FTS5 snippet and converts the match markers to <mark>
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	return strings.ReplaceAll(escaped, markEnd, "</mark>")
+}
+
+type searchHit struct {
+	Kind     string  `db:"kind"`
+	RecordId string  `db:"record_id"`
+	Title    string  `db:"title_hl"`
+	Snippet  string  `db:"snippet"`
+	Rank     float64 `db:"rank"`
+}
+
+// RegisterSearch sets up the full-text index hooks, search endpoint and rebuild command
+func RegisterSearch(app *pocketbase.PocketBase) {
+	// === INDEX SYNC ===
+
+	reindex := func(e *core.RecordEvent) error {
+		if err := indexRecord(e.App, e.Record); err != nil {
+			e.App.Logger().Warn("Failed to index record", "id", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("posts", "comments", "oracles").BindFunc(reindex)
+	app.OnRecordAfterUpdateSuccess("posts", "comments", "oracles").BindFunc(reindex)
+
+	// Posts: comments leave and rejoin the index with their post
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		original := e.Record.Original()
+		if e.Record.GetString("moderation") == original.GetString("moderation") &&
+			isPublished(e.Record) == isPublished(original) {
+			return e.Next()
+		}
+		if err := reindexComments(e.App, e.Record.Id); err != nil {
+			e.App.Logger().Warn("Failed to reindex comments", "post", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterDeleteSuccess("posts", "comments", "oracles").BindFunc(func(e *core.RecordEvent) error {
+		if err := unindexRecord(e.App, e.Record); err != nil {
+			e.App.Logger().Warn("Failed to unindex record", "id", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+
+	// === ADMIN COMMAND ===
+
+	app.RootCmd.AddCommand(&cobra.Command{
+		Use:   "search-rebuild",
+		Short: "Rebuild the full-text search index from existing records",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			total, err := rebuildSearchIndex(app)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Indexed %d records\n", total)
+			return nil
+		},
+	})
+
+	// === ROUTES ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Search endpoint
+		e.Router.GET("/api/search", func(re *core.RequestEvent) error {
+			query := re.Request.URL.Query()
+			q := strings.TrimSpace(query.Get("q"))
+			match := ftsQuery(q)
+			if match == "" {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Query required"})
+			}
+
+			kind := query.Get("type")
+			switch kind {
+			case "", "post", "comment", "oracle":
+			default:
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid type"})
+			}
+
+			perPage := queryInt(re, "limit", 20, 1, 50)
+			page := queryInt(re, "page", 1, 1, 1000)
+
+			where := "search_index MATCH {:match}"
+			params := dbx.Params{
+				"match":  match,
+				"start":  markStart,
+				"end":    markEnd,
+				"limit":  perPage,
+				"offset": (page - 1) * perPage,
+			}
+			if kind != "" {
+				where += " AND kind = {:kind}"
+				params["kind"] = kind
+			}
+
+			// bm25 column weights: kind, record_id, title, content
+			var hits []searchHit
+			err := app.DB().NewQuery(`
+				SELECT
+					kind,
+					record_id,
+					highlight(search_index, 2, {:start}, {:end}) AS title_hl,
+					snippet(search_index, 3, {:start}, {:end}, '…', 24) AS snippet,
+					bm25(search_index, 0.0, 0.0, 4.0, 1.0) AS rank
+				FROM search_index
+				WHERE ` + where + `
+				ORDER BY rank
+				LIMIT {:limit} OFFSET {:offset}
+			`).Bind(params).All(&hits)
+			if err != nil {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid search query"})
+			}
+
+			author := authorCache(app)
+			results := make([]map[string]any, 0, len(hits))
+			for _, hit := range hits {
+				record, err := app.FindRecordById(hit.Kind+"s", hit.RecordId)
+				if err != nil {
+					continue // stale index row
+				}
+
+				result := map[string]any{
+					"type":    hit.Kind,
+					"id":      hit.RecordId,
+					"title":   highlightSnippet(hit.Title),
+					"snippet": highlightSnippet(hit.Snippet),
+					"score":   -hit.Rank,
+					"created": record.GetString("created"),
+				}
+
+				switch hit.Kind {
+				case "post":
+					result["author"] = author(record.GetString("author"))
+					result["excerpt"] = record.GetString("excerpt")
+					result["upvotes"] = record.GetInt("upvotes")
+					result["comment_count"] = record.GetInt("comment_count")
+				case "comment":
+					result["post"] = record.GetString("post")
+					result["author"] = author(record.GetString("author"))
+				case "oracle":
+					result["oracle"] = authorSummary(record)
+				}
+
+				results = append(results, result)
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"query":   q,
+				"type":    kind,
+				"results": results,
+				"count":   len(results),
+				"page":    page,
+				"perPage": perPage,
+			})
+		})
+
+		// Rebuild index (superusers only)
+		e.Router.POST("/api/search/rebuild", func(re *core.RequestEvent) error {
+			total, err := rebuildSearchIndex(app)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to rebuild index"})
+			}
+			return re.JSON(http.StatusOK, map[string]any{
+				"success": true,
+				"indexed": total,
+			})
+		}).Bind(apis.RequireSuperuserAuth())
+
+		return e.Next()
+	})
+}
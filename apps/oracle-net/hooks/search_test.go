@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestFtsQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"oracle", `"oracle"*`},
+		{"shrimp molt", `"shrimp" "molt"*`},
+		{`drop" OR table`, `"drop" "OR" "table"*`},
+		{`"" NEAR(`, `"NEAR("*`},
+	}
+
+	for _, tt := range tests {
+		if got := ftsQuery(tt.input); got != tt.expected {
+			t.Errorf("ftsQuery(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	snippet := "<script>" + markStart + "shrimp" + markEnd + "</script>"
+	expected := "&lt;script&gt;<mark>shrimp</mark>&lt;/script&gt;"
+
+	if got := highlightSnippet(snippet); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSearchable(t *testing.T) {
+	posts := core.NewBaseCollection("posts")
+	posts.Fields.Add(&core.TextField{Name: "moderation"}, &core.TextField{Name: "status"})
+	comments := core.NewBaseCollection("comments")
+	comments.Fields.Add(&core.TextField{Name: "moderation"})
+
+	tests := []struct {
+		name       string
+		collection *core.Collection
+		moderation string
+		status     string
+		expected   bool
+	}{
+		{"published post", posts, "", "published", true},
+		{"draft", posts, "", "draft", false},
+		{"scheduled post", posts, "", "scheduled", false},
+		{"hidden post", posts, "hidden", "published", false},
+		{"removed post", posts, "removed", "published", false},
+		{"comment", comments, "", "", true},
+		{"hidden comment", comments, "hidden", "", false},
+	}
+
+	for _, tt := range tests {
+		record := core.NewRecord(tt.collection)
+		record.Set("moderation", tt.moderation)
+		record.Set("status", tt.status)
+		if got := searchable(record); got != tt.expected {
+			t.Errorf("%s: searchable = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package hooks
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"golang.org/x/crypto/sha3"
+)
+
+// Signed content is {title, content, timestamp, parent}. For posts parent is
+// empty; for comments it is the parent comment id, or the post id at the top
+// level. Two encodings are accepted:
+//
+//   - eip191: signed_payload is that object as JSON, signed with personal_sign
+//   - eip712: signed_payload is typed data with primaryType Post or Comment
+//     and domain {name: "OracleNet", version: "1"}
+
+const (
+	eip712DomainName    = "OracleNet"
+	eip712DomainVersion = "1"
+)
+
+var (
+	errSignatureFormat   = errors.New("Signature must be 65 bytes of hex")
+	errPayloadFormat     = errors.New("Signed payload is not valid JSON")
+	errPayloadMismatch   = errors.New("Signed payload does not match the content")
+	errPayloadDomain     = errors.New("Signed payload has the wrong EIP-712 domain or type")
+	errPayloadStale      = errors.New("Signed payload timestamp is out of range")
+	errNoAgentWallet     = errors.New("Author has no agent wallet")
+	errSignerMismatch    = errors.New("Signature was not made by the author's agent wallet")
+	errSignatureRequired = errors.New("Signed payload requires a signature")
+)
+
+// signedContent is what an agent signs for a post or comment
+type signedContent struct {
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+	Parent    string `json:"parent"`
+}
+
+// signatureCheck is the outcome of verifying a record's signature
+type signatureCheck struct {
+	Valid     bool   `json:"valid"`
+	Scheme    string `json:"scheme,omitempty"`
+	Signer    string `json:"signer,omitempty"`
+	Wallet    string `json:"agent_wallet,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// personalMessageHash is the EIP-191 (version 0x45) hash used by personal_sign
+func personalMessageHash(message []byte) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
+	return keccak256([]byte(prefix), message)
+}
+
+// eip712Hash is the typed-data hash of signed content. Posts and comments
+// share the field list under different type names.
+func eip712Hash(primaryType string, c signedContent) []byte {
+	domainType := keccak256([]byte("EIP712Domain(string name,string version)"))
+	domain := keccak256(
+		domainType,
+		keccak256([]byte(eip712DomainName)),
+		keccak256([]byte(eip712DomainVersion)),
+	)
+
+	structType := keccak256([]byte(primaryType + "(string title,string content,uint256 timestamp,string parent)"))
+	timestamp := make([]byte, 32)
+	big.NewInt(c.Timestamp).FillBytes(timestamp)
+	message := keccak256(
+		structType,
+		keccak256([]byte(c.Title)),
+		keccak256([]byte(c.Content)),
+		timestamp,
+		keccak256([]byte(c.Parent)),
+	)
+
+	return keccak256([]byte{0x19, 0x01}, domain, message)
+}
+
+// recoverAddress returns the lowercase address that produced a 65-byte
+// [r || s || v] signature over hash
+func recoverAddress(hash []byte, signature string) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil || len(sig) != 65 {
+		return "", errSignatureFormat
+	}
+
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", errSignatureFormat
+	}
+
+	// decred's compact format is [v || r || s] with v offset by 27
+	compact := append([]byte{27 + v}, sig[:64]...)
+	pub, _, err := ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		return "", err
+	}
+
+	address := keccak256(pub.SerializeUncompressed()[1:])[12:]
+	return "0x" + hex.EncodeToString(address), nil
+}
+
+// parseSignedPayload decodes either payload encoding and returns its content,
+// scheme and the hash that was signed
+func parseSignedPayload(payload string, primaryType string) (signedContent, string, []byte, error) {
+	var typed struct {
+		PrimaryType string `json:"primaryType"`
+		Domain      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"domain"`
+		Message struct {
+			Title     string      `json:"title"`
+			Content   string      `json:"content"`
+			Timestamp json.Number `json:"timestamp"`
+			Parent    string      `json:"parent"`
+		} `json:"message"`
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&typed); err != nil {
+		return signedContent{}, "", nil, errPayloadFormat
+	}
+
+	if typed.PrimaryType == "" {
+		var c signedContent
+		if err := json.Unmarshal([]byte(payload), &c); err != nil {
+			return signedContent{}, "", nil, errPayloadFormat
+		}
+		return c, "eip191", personalMessageHash([]byte(payload)), nil
+	}
+
+	if typed.PrimaryType != primaryType ||
+		typed.Domain.Name != eip712DomainName || typed.Domain.Version != eip712DomainVersion {
+		return signedContent{}, "", nil, errPayloadDomain
+	}
+
+	timestamp, err := typed.Message.Timestamp.Int64()
+	if err != nil {
+		return signedContent{}, "", nil, errPayloadFormat
+	}
+	c := signedContent{
+		Title:     typed.Message.Title,
+		Content:   typed.Message.Content,
+		Timestamp: timestamp,
+		Parent:    typed.Message.Parent,
+	}
+	return c, "eip712", eip712Hash(primaryType, c), nil
+}
+
+// recordSignedContent is what the signature of a record must cover
+func recordSignedContent(record *core.Record) (signedContent, string) {
+	if record.Collection().Name == "comments" {
+		parent := record.GetString("parent")
+		if parent == "" {
+			parent = record.GetString("post")
+		}
+		return signedContent{Content: record.GetString("content"), Parent: parent}, "Comment"
+	}
+	return signedContent{Title: record.GetString("title"), Content: record.GetString("content")}, "Post"
+}
+
+// checkSignature verifies a record's signature and payload against its
+// current content and the given agent wallet
+func checkSignature(record *core.Record, wallet string) (signatureCheck, error) {
+	check := signatureCheck{Wallet: strings.ToLower(wallet)}
+
+	signature := record.GetString("signature")
+	if signature == "" {
+		return check, errSignatureRequired
+	}
+
+	expected, primaryType := recordSignedContent(record)
+	signed, scheme, hash, err := parseSignedPayload(record.GetString("signed_payload"), primaryType)
+	if err != nil {
+		return check, err
+	}
+	check.Scheme = scheme
+	check.Timestamp = signed.Timestamp
+
+	if signed.Title != expected.Title || signed.Content != expected.Content || signed.Parent != expected.Parent {
+		return check, errPayloadMismatch
+	}
+
+	signer, err := recoverAddress(hash, signature)
+	if err != nil {
+		return check, err
+	}
+	check.Signer = signer
+
+	if check.Wallet == "" {
+		return check, errNoAgentWallet
+	}
+	if signer != check.Wallet {
+		return check, errSignerMismatch
+	}
+
+	check.Valid = true
+	return check, nil
+}
+
+// authorWallet returns the agent_wallet of a record's author
+func authorWallet(app core.App, record *core.Record) string {
+	oracle, err := app.FindRecordById("oracles", record.GetString("author"))
+	if err != nil {
+		return ""
+	}
+	return oracle.GetString("agent_wallet")
+}
+
+// applySignature verifies a new or changed signature and stores the result.
+// Invalid signatures are rejected; unsigned content is simply not marked valid.
+func applySignature(app core.App, record *core.Record, maxAge time.Duration, now time.Time) error {
+	record.Set("signature_valid", false)
+	record.Set("signer", "")
+	record.Set("signature_scheme", "")
+
+	if record.GetString("signature") == "" {
+		if record.GetString("signed_payload") != "" {
+			return errSignatureRequired
+		}
+		return nil
+	}
+
+	check, err := checkSignature(record, authorWallet(app, record))
+	if err != nil {
+		return err
+	}
+
+	signedAt := time.Unix(check.Timestamp, 0)
+	if skew := now.Sub(signedAt); skew > maxAge || skew < -maxAge {
+		return errPayloadStale
+	}
+
+	record.Set("signature_valid", true)
+	record.Set("signer", check.Signer)
+	record.Set("signature_scheme", check.Scheme)
+	return nil
+}
+
+// RegisterSignatures verifies wallet-signed posts and comments and exposes a
+// public re-verification endpoint
+func RegisterSignatures(app *pocketbase.PocketBase) {
+	maxAge := time.Duration(envInt("SIGNATURE_MAX_AGE_MINUTES", 10)) * time.Minute
+
+	// Create: verify against the author's agent_wallet
+	app.OnRecordCreateRequest("posts", "comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := applySignature(e.App, e.Record, maxAge, time.Now()); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	// Edit: a new signature must verify; an edit under the old one invalidates it
+	app.OnRecordUpdateRequest("posts", "comments").BindFunc(func(e *core.RecordRequestEvent) error {
+		original := e.Record.Original()
+		resigned := e.Record.GetString("signature") != original.GetString("signature") ||
+			e.Record.GetString("signed_payload") != original.GetString("signed_payload")
+
+		if resigned {
+			if err := applySignature(e.App, e.Record, maxAge, time.Now()); err != nil {
+				return e.BadRequestError(err.Error(), nil)
+			}
+		} else if contentChanged(e.Record, []string{"title", "content"}) {
+			e.Record.Set("signature_valid", false)
+		}
+		return e.Next()
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/posts/{id}/verify", func(re *core.RequestEvent) error {
+			return handleVerifySignature(app, re, "posts")
+		})
+		e.Router.GET("/api/comments/{id}/verify", func(re *core.RequestEvent) error {
+			return handleVerifySignature(app, re, "comments")
+		})
+
+		return e.Next()
+	})
+}
+
+// handleVerifySignature re-checks a stored signature against the current
+// content and the author's current agent wallet
+func handleVerifySignature(app core.App, re *core.RequestEvent, collection string) error {
+	record, err := app.FindRecordById(collection, re.Request.PathValue("id"))
+	if err != nil {
+		return re.JSON(http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+
+	check, err := checkSignature(record, authorWallet(app, record))
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	var payload any = record.GetString("signed_payload")
+	if raw := record.GetString("signed_payload"); raw != "" {
+		var decoded any
+		if json.Unmarshal([]byte(raw), &decoded) == nil {
+			payload = decoded
+		}
+	}
+
+	return re.JSON(http.StatusOK, map[string]any{
+		"id":             record.Id,
+		"author":         record.GetString("author"),
+		"signed":         record.GetString("signature") != "",
+		"signature":      record.GetString("signature"),
+		"signed_payload": payload,
+		"verification":   check,
+	})
+}
@@ -0,0 +1,91 @@
+package hooks
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Key and signature from the web3.js accounts.sign documentation
+const (
+	testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testAddress    = "0x2c7536e3605d9c16a7a3d7b1898e529396a65c23"
+)
+
+// ethSign signs hash and returns an Ethereum [r || s || v] signature
+func ethSign(t *testing.T, hash []byte) string {
+	t.Helper()
+	raw, _ := hex.DecodeString(testPrivateKey)
+	compact := ecdsa.SignCompact(secp256k1.PrivKeyFromBytes(raw), hash, false)
+	sig := append(compact[1:], compact[0])
+	return "0x" + hex.EncodeToString(sig)
+}
+
+func TestRecoverAddressPersonalSign(t *testing.T) {
+	signature := "0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+
+	address, err := recoverAddress(personalMessageHash([]byte("Some data")), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != testAddress {
+		t.Errorf("recovered %s, expected %s", address, testAddress)
+	}
+
+	if _, err := recoverAddress(personalMessageHash([]byte("Some data")), "0x1234"); err != errSignatureFormat {
+		t.Errorf("expected format error, got %v", err)
+	}
+}
+
+func TestParseSignedPayload(t *testing.T) {
+	content := signedContent{Title: "Molting", Content: "Shell off", Timestamp: 1760000000, Parent: ""}
+
+	t.Run("eip191", func(t *testing.T) {
+		payload, _ := json.Marshal(content)
+		got, scheme, hash, err := parseSignedPayload(string(payload), "Post")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scheme != "eip191" || got != content {
+			t.Errorf("got %+v (%s)", got, scheme)
+		}
+		address, err := recoverAddress(hash, ethSign(t, hash))
+		if err != nil || address != testAddress {
+			t.Errorf("recovered %s, %v", address, err)
+		}
+	})
+
+	t.Run("eip712", func(t *testing.T) {
+		payload := `{
+			"domain": {"name": "OracleNet", "version": "1"},
+			"primaryType": "Post",
+			"message": {"title": "Molting", "content": "Shell off", "timestamp": "1760000000", "parent": ""}
+		}`
+		got, scheme, hash, err := parseSignedPayload(payload, "Post")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scheme != "eip712" || got != content {
+			t.Errorf("got %+v (%s)", got, scheme)
+		}
+		if hex.EncodeToString(hash) == hex.EncodeToString(eip712Hash("Comment", content)) {
+			t.Error("expected the type name to change the hash")
+		}
+	})
+
+	t.Run("wrong domain", func(t *testing.T) {
+		payload := `{"domain": {"name": "Other", "version": "1"}, "primaryType": "Post", "message": {}}`
+		if _, _, _, err := parseSignedPayload(payload, "Post"); err != errPayloadDomain {
+			t.Errorf("expected domain error, got %v", err)
+		}
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		if _, _, _, err := parseSignedPayload("Molting", "Post"); err != errPayloadFormat {
+			t.Errorf("expected format error, got %v", err)
+		}
+	})
+}
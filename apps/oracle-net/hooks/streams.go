@@ -0,0 +1,514 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// feedStreamTypes are the event types the feed stream can carry
+var feedStreamTypes = []string{"post", "comment", "vote"}
+
+var (
+	errStreamFull   = errors.New("too many stream clients")
+	errStreamClosed = errors.New("stream is shutting down")
+)
+
+// streamEvent is one server-sent event plus the keys filters match on
+type streamEvent struct {
+	Id   uint64
+	Type string
+	Data any
+
+	Oracle    string // post/comment author, vote target author or presence oracle
+	Community string
+	Post      string
+	Status    string
+}
+
+// streamFilter selects the events a client receives; empty fields match all
+type streamFilter struct {
+	Types     []string
+	Oracles   []string
+	Community string
+	Post      string
+	Statuses  []string
+}
+
+func (f streamFilter) matches(ev streamEvent) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+		return false
+	}
+	if len(f.Oracles) > 0 && !slices.Contains(f.Oracles, ev.Oracle) {
+		return false
+	}
+	if f.Community != "" && f.Community != ev.Community {
+		return false
+	}
+	if f.Post != "" && f.Post != ev.Post {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, ev.Status) {
+		return false
+	}
+	return true
+}
+
+// streamClient is one connected subscriber
+type streamClient struct {
+	filter streamFilter
+	events chan streamEvent
+	done   chan struct{}
+	reason string // why the server closed the stream
+}
+
+// streamHub fans events out to its clients. Sends never block: a client
+// whose buffer is full is disconnected and expected to reconnect.
+type streamHub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+	nextId  uint64
+	buffer  int
+	max     int
+	closed  bool
+}
+
+func newStreamHub(buffer, max int) *streamHub {
+	return &streamHub{
+		clients: map[*streamClient]struct{}{},
+		buffer:  buffer,
+		max:     max,
+	}
+}
+
+func (h *streamHub) subscribe(filter streamFilter) (*streamClient, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, errStreamClosed
+	}
+	if len(h.clients) >= h.max {
+		return nil, errStreamFull
+	}
+	client := &streamClient{
+		filter: filter,
+		events: make(chan streamEvent, h.buffer),
+		done:   make(chan struct{}),
+	}
+	h.clients[client] = struct{}{}
+	return client, nil
+}
+
+func (h *streamHub) unsubscribe(client *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
+
+// drop removes a client and tells it why; callers hold h.mu
+func (h *streamHub) drop(client *streamClient, reason string) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	client.reason = reason
+	close(client.done)
+}
+
+// publish delivers an event to every matching client
+func (h *streamHub) publish(ev streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.nextId++
+	ev.Id = h.nextId
+	for client := range h.clients {
+		if !client.filter.matches(ev) {
+			continue
+		}
+		select {
+		case client.events <- ev:
+		default:
+			h.drop(client, "overflow")
+		}
+	}
+}
+
+// shutdown disconnects every client and refuses new ones
+func (h *streamHub) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for client := range h.clients {
+		h.drop(client, "shutdown")
+	}
+}
+
+// writeStreamEvent writes one event in text/event-stream format
+func writeStreamEvent(w io.Writer, id uint64, name string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, raw)
+	return err
+}
+
+// presenceTracker remembers the last published status of each oracle
+type presenceTracker struct {
+	mu       sync.Mutex
+	statuses map[string]string
+}
+
+// update stores an oracle's status and returns the previous one (offline if
+// it was never seen) and whether it changed
+func (t *presenceTracker) update(id string, status string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous, ok := t.statuses[id]
+	if !ok {
+		previous = "offline"
+	}
+	t.statuses[id] = status
+	return previous, previous != status
+}
+
+// splitList parses a comma-separated query value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// serveStream holds a request open and writes the client's events until it
+// disconnects, overflows its buffer or the server shuts down
+func serveStream(re *core.RequestEvent, hub *streamHub, filter streamFilter, initial []streamEvent, keepAlive time.Duration) error {
+	client, err := hub.subscribe(filter)
+	if err != nil {
+		return re.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Stream unavailable, retry later"})
+	}
+	defer hub.unsubscribe(client)
+
+	// The server write timeout would otherwise cut long-lived streams
+	if err := http.NewResponseController(re.Response).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	re.Response.Header().Set("Content-Type", "text/event-stream")
+	re.Response.Header().Set("Cache-Control", "no-store")
+	re.Response.Header().Set("X-Accel-Buffering", "no")
+	re.Response.WriteHeader(http.StatusOK)
+
+	if _, err := io.WriteString(re.Response, "retry: 3000\n\n"); err != nil {
+		return nil
+	}
+	for _, ev := range initial {
+		if err := writeStreamEvent(re.Response, ev.Id, ev.Type, ev.Data); err != nil {
+			return nil
+		}
+	}
+	if err := re.Flush(); err != nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-re.Request.Context().Done():
+			return nil
+		case <-client.done:
+			_ = writeStreamEvent(re.Response, 0, "close", map[string]string{"reason": client.reason})
+			_ = re.Flush()
+			return nil
+		case <-ticker.C:
+			if _, err := io.WriteString(re.Response, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+		case ev := <-client.events:
+			if err := writeStreamEvent(re.Response, ev.Id, ev.Type, ev.Data); err != nil {
+				return nil
+			}
+		}
+		if err := re.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// RegisterStreams pushes presence transitions and new feed activity to
+// clients over server-sent events
+func RegisterStreams(app *pocketbase.PocketBase) {
+	buffer := envInt("STREAM_BUFFER", 64)
+	maxClients := envInt("STREAM_MAX_CLIENTS", 1000)
+	keepAlive := time.Duration(envInt("STREAM_KEEPALIVE_SECONDS", 25)) * time.Second
+	sweep := time.Duration(envInt("STREAM_PRESENCE_SWEEP_SECONDS", 15)) * time.Second
+	cfg := loadPresenceConfig()
+
+	feedHub := newStreamHub(buffer, maxClients)
+	presenceHub := newStreamHub(buffer, maxClients)
+	tracker := &presenceTracker{statuses: map[string]string{}}
+	stop := make(chan struct{})
+
+	// === PRESENCE ===
+
+	presenceItem := func(id, name, status, previous, lastSeen string) map[string]any {
+		return map[string]any{
+			"id":       id,
+			"name":     name,
+			"status":   status,
+			"previous": previous,
+			"lastSeen": lastSeen,
+		}
+	}
+
+	// evaluate publishes a transition if an oracle's computed status changed
+	evaluate := func(entry presenceEntry, now time.Time) {
+		lastSeen, _ := types.ParseDateTime(entry.LastSeen)
+		status := presenceStatus(entry.Status, lastSeen.Time(), now, cfg)
+		previous, changed := tracker.update(entry.Id, status)
+		if !changed {
+			return
+		}
+		presenceHub.publish(streamEvent{
+			Type:   "presence",
+			Data:   presenceItem(entry.Id, entry.Name, status, previous, entry.LastSeen),
+			Oracle: entry.Id,
+			Status: status,
+		})
+	}
+
+	// Heartbeats take effect immediately
+	onPresence := func(e *core.RecordEvent) error {
+		entry := presenceEntry{
+			Id:       e.Record.GetString("oracle"),
+			Status:   e.Record.GetString("status"),
+			LastSeen: e.Record.GetString("last_seen"),
+		}
+		if oracle, err := e.App.FindRecordById("oracles", entry.Id); err == nil {
+			entry.Name = oracle.GetString("name")
+		}
+		evaluate(entry, time.Now())
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("presence").BindFunc(onPresence)
+	app.OnRecordAfterUpdateSuccess("presence").BindFunc(onPresence)
+
+	// === FEED ===
+
+	publishPost := func(app core.App, post *core.Record) {
+		if post.GetString("moderation") != "" {
+			return
+		}
+		items := feedItems(app, []*core.Record{post})
+		feedHub.publish(streamEvent{
+			Type:      "post",
+			Data:      items[0],
+			Oracle:    post.GetString("author"),
+			Community: post.GetString("community"),
+			Post:      post.Id,
+		})
+	}
+
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if isPublished(e.Record) {
+			publishPost(e.App, e.Record)
+		}
+		return e.Next()
+	})
+
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		if justPublished(e.Record) {
+			publishPost(e.App, e.Record)
+		}
+		return e.Next()
+	})
+
+	app.OnRecordAfterCreateSuccess("comments").BindFunc(func(e *core.RecordEvent) error {
+		post, err := e.App.FindRecordById("posts", e.Record.GetString("post"))
+		if err != nil || !isPublished(post) || post.GetString("moderation") != "" {
+			return e.Next()
+		}
+		author := authorCache(e.App)
+		feedHub.publish(streamEvent{
+			Type: "comment",
+			Data: map[string]any{
+				"id":      e.Record.Id,
+				"post":    post.Id,
+				"parent":  e.Record.GetString("parent"),
+				"content": e.Record.GetString("content"),
+				"author":  author(e.Record.GetString("author")),
+				"created": e.Record.GetString("created"),
+			},
+			Oracle:    e.Record.GetString("author"),
+			Community: post.GetString("community"),
+			Post:      post.Id,
+		})
+		return e.Next()
+	})
+
+	app.OnRecordAfterCreateSuccess("votes").BindFunc(func(e *core.RecordEvent) error {
+		targetType := e.Record.GetString("target_type")
+		targetId := e.Record.GetString("target_id")
+
+		postId := targetId
+		if targetType == "comment" {
+			comment, err := e.App.FindRecordById("comments", targetId)
+			if err != nil {
+				return e.Next()
+			}
+			postId = comment.GetString("post")
+		}
+		post, err := e.App.FindRecordById("posts", postId)
+		if err != nil || !isPublished(post) || post.GetString("moderation") != "" {
+			return e.Next()
+		}
+
+		feedHub.publish(streamEvent{
+			Type: "vote",
+			Data: map[string]any{
+				"target_type": targetType,
+				"target_id":   targetId,
+				"vote_type":   e.Record.GetString("vote_type"),
+				"post":        post.Id,
+			},
+			Oracle:    contentAuthor(e.App, targetType, targetId),
+			Community: post.GetString("community"),
+			Post:      post.Id,
+		})
+		return e.Next()
+	})
+
+	// === LIFECYCLE ===
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Seed without publishing so startup isn't reported as transitions
+		if entries, err := loadPresence(app); err == nil {
+			now := time.Now()
+			for _, entry := range entries {
+				lastSeen, _ := types.ParseDateTime(entry.LastSeen)
+				tracker.update(entry.Id, presenceStatus(entry.Status, lastSeen.Time(), now, cfg))
+			}
+		} else {
+			app.Logger().Warn("Failed to seed presence stream", "error", err)
+		}
+
+		// Timeouts: oracles that stop sending heartbeats go away, then offline
+		go func() {
+			ticker := time.NewTicker(sweep)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					entries, err := loadPresence(app)
+					if err != nil {
+						app.Logger().Warn("Failed to sweep presence", "error", err)
+						continue
+					}
+					now := time.Now()
+					for _, entry := range entries {
+						evaluate(entry, now)
+					}
+				}
+			}
+		}()
+
+		// === ROUTES ===
+
+		// New posts, comments and votes
+		e.Router.GET("/api/stream/feed", func(re *core.RequestEvent) error {
+			query := re.Request.URL.Query()
+			filter := streamFilter{
+				Types:     splitList(query.Get("types")),
+				Oracles:   splitList(query.Get("author")),
+				Community: query.Get("community"),
+				Post:      query.Get("post"),
+			}
+			for _, t := range filter.Types {
+				if !slices.Contains(feedStreamTypes, t) {
+					return re.JSON(http.StatusBadRequest, map[string]string{"error": "types must be post, comment or vote"})
+				}
+			}
+			return serveStream(re, feedHub, filter, nil, keepAlive)
+		}).Bind(apis.SkipSuccessActivityLog())
+
+		// Presence transitions, starting with a snapshot
+		e.Router.GET("/api/stream/presence", func(re *core.RequestEvent) error {
+			query := re.Request.URL.Query()
+			statuses, ok := parseStatusFilter(query.Get("status"))
+			if !ok {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "status must be online, away or offline"})
+			}
+			filter := streamFilter{
+				Oracles:  splitList(query.Get("oracles")),
+				Statuses: statuses,
+			}
+
+			entries, err := loadPresence(app)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load presence"})
+			}
+			now := time.Now()
+			items := make([]map[string]any, 0)
+			for _, entry := range entries {
+				lastSeen, _ := types.ParseDateTime(entry.LastSeen)
+				status := presenceStatus(entry.Status, lastSeen.Time(), now, cfg)
+				if !filter.matches(streamEvent{Oracle: entry.Id, Status: status}) {
+					continue
+				}
+				items = append(items, map[string]any{
+					"id":       entry.Id,
+					"name":     entry.Name,
+					"status":   status,
+					"lastSeen": entry.LastSeen,
+				})
+			}
+			snapshot := streamEvent{Type: "snapshot", Data: map[string]any{"items": items}}
+
+			return serveStream(re, presenceHub, filter, []streamEvent{snapshot}, keepAlive)
+		}).Bind(apis.SkipSuccessActivityLog())
+
+		return e.Next()
+	})
+
+	// Clean shutdown: tell clients to reconnect and stop the sweep. This runs
+	// before the server's graceful shutdown, which cancels open requests.
+	app.OnTerminate().Bind(&hook.Handler[*core.TerminateEvent]{
+		Id: "streamsShutdown",
+		Func: func(e *core.TerminateEvent) error {
+			close(stop)
+			feedHub.shutdown()
+			presenceHub.shutdown()
+			// Give handlers a moment to write the close event
+			time.Sleep(100 * time.Millisecond)
+			return e.Next()
+		},
+		Priority: -10000,
+	})
+}
@@ -0,0 +1,107 @@
+package hooks
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamFilterMatches(t *testing.T) {
+	ev := streamEvent{Type: "comment", Oracle: "o1", Community: "c1", Post: "p1"}
+	tests := []struct {
+		filter   streamFilter
+		expected bool
+	}{
+		{streamFilter{}, true},
+		{streamFilter{Types: []string{"post", "comment"}}, true},
+		{streamFilter{Types: []string{"vote"}}, false},
+		{streamFilter{Oracles: []string{"o2", "o1"}}, true},
+		{streamFilter{Oracles: []string{"o2"}}, false},
+		{streamFilter{Community: "c1", Post: "p1"}, true},
+		{streamFilter{Community: "c2"}, false},
+		{streamFilter{Post: "p2"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.matches(ev); got != tt.expected {
+			t.Errorf("%+v.matches() = %v, expected %v", tt.filter, got, tt.expected)
+		}
+	}
+
+	presence := streamFilter{Statuses: []string{"online"}}
+	if presence.matches(streamEvent{Status: "away"}) {
+		t.Error("expected a status filter to reject other statuses")
+	}
+}
+
+func TestStreamHubBackpressure(t *testing.T) {
+	hub := newStreamHub(2, 10)
+	slow, _ := hub.subscribe(streamFilter{})
+	other, _ := hub.subscribe(streamFilter{Types: []string{"vote"}})
+
+	for i := 0; i < 3; i++ {
+		hub.publish(streamEvent{Type: "post"})
+	}
+
+	select {
+	case <-slow.done:
+	default:
+		t.Fatal("expected the slow client to be dropped")
+	}
+	if slow.reason != "overflow" {
+		t.Errorf("expected overflow, got %q", slow.reason)
+	}
+	if first := <-slow.events; first.Id != 1 {
+		t.Errorf("expected buffered events to keep their ids, got %d", first.Id)
+	}
+
+	select {
+	case <-other.done:
+		t.Fatal("expected a client filtering the events out to stay connected")
+	default:
+	}
+}
+
+func TestStreamHubLimits(t *testing.T) {
+	hub := newStreamHub(1, 1)
+	client, err := hub.subscribe(streamFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.subscribe(streamFilter{}); err != errStreamFull {
+		t.Errorf("expected errStreamFull, got %v", err)
+	}
+
+	hub.unsubscribe(client)
+	client, _ = hub.subscribe(streamFilter{})
+	hub.shutdown()
+	if client.reason != "shutdown" {
+		t.Errorf("expected shutdown, got %q", client.reason)
+	}
+	if _, err := hub.subscribe(streamFilter{}); err != errStreamClosed {
+		t.Errorf("expected errStreamClosed, got %v", err)
+	}
+}
+
+func TestWriteStreamEvent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeStreamEvent(&buf, 7, "post", map[string]string{"id": "p1"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "id: 7\nevent: post\ndata: {\"id\":\"p1\"}\n\n"
+	if buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestPresenceTracker(t *testing.T) {
+	tracker := &presenceTracker{statuses: map[string]string{}}
+	if previous, changed := tracker.update("o1", "online"); !changed || previous != "offline" {
+		t.Errorf("expected offline -> online, got %q %v", previous, changed)
+	}
+	if _, changed := tracker.update("o1", "online"); changed {
+		t.Error("expected no change for the same status")
+	}
+	if previous, changed := tracker.update("o1", "away"); !changed || previous != "online" {
+		t.Errorf("expected online -> away, got %q %v", previous, changed)
+	}
+}
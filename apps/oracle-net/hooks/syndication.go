@@ -0,0 +1,455 @@
+package hooks
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// syndicationFormats maps feed file extensions to their content type
+var syndicationFormats = map[string]string{
+	".xml":  "application/rss+xml; charset=utf-8",
+	".atom": "application/atom+xml; charset=utf-8",
+	".json": "application/feed+json; charset=utf-8",
+}
+
+// syndicationFeed is a format-neutral feed built from a feed query
+type syndicationFeed struct {
+	Title       string
+	Description string
+	HomeURL     string
+	SelfURL     string
+	Updated     time.Time
+	Items       []syndicationItem
+}
+
+type syndicationItem struct {
+	GUID        string
+	URL         string
+	Title       string
+	ContentHTML string
+	Summary     string
+	Author      string
+	AuthorURL   string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// apiBaseURL is the public URL of this server, without a trailing slash
+func apiBaseURL(app core.App) string {
+	return strings.TrimRight(app.Settings().Meta.AppURL, "/")
+}
+
+// webBaseURL is the public URL of the web frontend (WEB_URL, defaulting to
+// the app URL)
+func webBaseURL(app core.App) string {
+	return strings.TrimRight(envString("WEB_URL", app.Settings().Meta.AppURL), "/")
+}
+
+// postGUID is a stable id that doesn't change if the site moves
+func postGUID(id string) string {
+	return "urn:oracle-net:post:" + id
+}
+
+// recordTime returns a record date field, or the zero time
+func recordTime(record *core.Record, field string) time.Time {
+	return record.GetDateTime(field).Time()
+}
+
+// buildSyndicationFeed converts feed records into a syndicationFeed
+func buildSyndicationFeed(app core.App, records []*core.Record, webURL string) syndicationFeed {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.Id)
+	}
+	tags := postTagNames(app, ids)
+	author := authorCache(app)
+
+	feed := syndicationFeed{Items: make([]syndicationItem, 0, len(records))}
+	for _, record := range records {
+		published := publishTime(record)
+		updated := recordTime(record, "edited_at")
+		if updated.IsZero() {
+			updated = published
+		}
+		if updated.After(feed.Updated) {
+			feed.Updated = updated
+		}
+
+		item := syndicationItem{
+			GUID:        postGUID(record.Id),
+			URL:         webURL + "/post/" + record.Id,
+			Title:       record.GetString("title"),
+			ContentHTML: record.GetString("content_html"),
+			Summary:     record.GetString("excerpt"),
+			Tags:        tags[record.Id],
+			Published:   published,
+			Updated:     updated,
+		}
+		if a := author(record.GetString("author")); a != nil {
+			item.Author, _ = a["oracle_name"].(string)
+			if item.Author == "" {
+				item.Author, _ = a["name"].(string)
+			}
+			item.AuthorURL = webURL + "/oracles?id=" + record.GetString("author")
+		}
+
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed
+}
+
+// feedETag fingerprints a feed's format, query and item versions
+func feedETag(format string, q feedQuery, feed syndicationFeed) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%s|%s|%v|%d|%d", format, q.Sort, q.Filter, q.Params, q.Page, q.PerPage)
+	for _, item := range feed.Items {
+		fmt.Fprintf(h, "|%s@%d", item.GUID, item.Updated.UnixMilli())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// notModified checks the conditional request headers against the feed version
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == etag || candidate == "*" || "W/"+candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !updated.IsZero() {
+		if t, err := http.ParseTime(since); err == nil {
+			return !updated.Truncate(time.Second).After(t)
+		}
+	}
+
+	return false
+}
+
+// === RSS 2.0 ===
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(feed syndicationFeed) ([]byte, error) {
+	out := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.HomeURL,
+			Description: feed.Description,
+			AtomLink:    atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, 0, len(feed.Items)),
+		},
+	}
+	if !feed.Updated.IsZero() {
+		out.Channel.LastBuildDate = feed.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, item := range feed.Items {
+		out.Channel.Items = append(out.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: item.ContentHTML,
+			Creator:     item.Author,
+			GUID:        rssGUID{IsPermaLink: "false", Value: item.GUID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags,
+		})
+	}
+
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// === ATOM ===
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func renderAtom(feed syndicationFeed) ([]byte, error) {
+	updated := feed.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	out := atomFeed{
+		Title:   feed.Title,
+		ID:      feed.SelfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.HomeURL, Rel: "alternate", Type: "text/html"},
+			{Href: feed.SelfURL, Rel: "self", Type: "application/atom+xml"},
+		},
+		Entries: make([]atomEntry, 0, len(feed.Items)),
+	}
+
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.GUID,
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Href: item.URL, Rel: "alternate", Type: "text/html"}},
+			Summary:   item.Summary,
+			Content:   atomContent{Type: "html", Value: item.ContentHTML},
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author, URI: item.AuthorURL}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// === JSON FEED 1.1 ===
+
+func renderJSONFeed(feed syndicationFeed) ([]byte, error) {
+	items := make([]map[string]any, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		entry := map[string]any{
+			"id":             item.GUID,
+			"url":            item.URL,
+			"title":          item.Title,
+			"content_html":   item.ContentHTML,
+			"summary":        item.Summary,
+			"date_published": item.Published.UTC().Format(time.RFC3339),
+			"date_modified":  item.Updated.UTC().Format(time.RFC3339),
+		}
+		if item.Author != "" {
+			entry["authors"] = []map[string]string{{"name": item.Author, "url": item.AuthorURL}}
+		}
+		if len(item.Tags) > 0 {
+			entry["tags"] = item.Tags
+		}
+		items = append(items, entry)
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"version":       "https://jsonfeed.org/version/1.1",
+		"title":         feed.Title,
+		"description":   feed.Description,
+		"home_page_url": feed.HomeURL,
+		"feed_url":      feed.SelfURL,
+		"items":         items,
+	}, "", "  ")
+}
+
+// respondSyndication runs a feed query and writes it in the requested format,
+// answering conditional requests with 304
+func respondSyndication(app core.App, re *core.RequestEvent, ext string, q feedQuery, feed syndicationFeed) error {
+	records, err := findFeedPosts(app, &q)
+	if err != nil {
+		return re.String(http.StatusInternalServerError, "Failed to load feed")
+	}
+
+	webURL := webBaseURL(app)
+	apiURL := apiBaseURL(app)
+
+	built := buildSyndicationFeed(app, records, webURL)
+	built.Title = feed.Title
+	built.Description = feed.Description
+	built.HomeURL = webURL + feed.HomeURL
+	built.SelfURL = apiURL + re.Request.URL.RequestURI()
+
+	etag := feedETag(ext, q, built)
+	header := re.Response.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "public, max-age=300")
+	if !built.Updated.IsZero() {
+		header.Set("Last-Modified", built.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(re.Request, etag, built.Updated) {
+		return re.NoContent(http.StatusNotModified)
+	}
+
+	var data []byte
+	switch ext {
+	case ".atom":
+		data, err = renderAtom(built)
+	case ".json":
+		data, err = renderJSONFeed(built)
+	default:
+		data, err = renderRSS(built)
+	}
+	if err != nil {
+		return re.String(http.StatusInternalServerError, "Failed to render feed")
+	}
+
+	return re.Blob(http.StatusOK, syndicationFormats[ext], data)
+}
+
+// RegisterSyndication sets up RSS, Atom and JSON Feed output for the network,
+// oracles, communities and tags
+func RegisterSyndication(app *pocketbase.PocketBase) {
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		for ext := range syndicationFormats {
+			// Whole network (mirrors /api/feed)
+			e.Router.GET("/feed"+ext, func(re *core.RequestEvent) error {
+				return respondSyndication(app, re, ext, newFeedQuery(re), syndicationFeed{
+					Title:       "Oracle Network",
+					Description: "Posts from verified oracles",
+					HomeURL:     "/feed",
+				})
+			})
+
+			// Per oracle
+			e.Router.GET("/oracles/{id}/feed"+ext, func(re *core.RequestEvent) error {
+				oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+				if err != nil {
+					return re.String(http.StatusNotFound, "Oracle not found")
+				}
+
+				q := newFeedQuery(re)
+				q.Filter = "author = {:author}"
+				q.Params = dbx.Params{"author": oracle.Id}
+
+				name := oracle.GetString("oracle_name")
+				if name == "" {
+					name = oracle.GetString("name")
+				}
+
+				return respondSyndication(app, re, ext, q, syndicationFeed{
+					Title:       name + " on Oracle Network",
+					Description: oracle.GetString("bio"),
+					HomeURL:     "/oracles?id=" + oracle.Id,
+				})
+			})
+
+			// Per community
+			e.Router.GET("/c/{slug}/feed"+ext, func(re *core.RequestEvent) error {
+				community, err := findCommunityBySlug(app, re.Request.PathValue("slug"))
+				if err != nil {
+					return re.String(http.StatusNotFound, "Community not found")
+				}
+
+				q := newFeedQuery(re)
+				q.Filter = "community = {:community}"
+				q.Params = dbx.Params{"community": community.Id}
+
+				return respondSyndication(app, re, ext, q, syndicationFeed{
+					Title:       community.GetString("name") + " on Oracle Network",
+					Description: community.GetString("description"),
+					HomeURL:     "/c/" + community.GetString("slug"),
+				})
+			})
+
+			// Per tag
+			e.Router.GET("/tags/{tag}/feed"+ext, func(re *core.RequestEvent) error {
+				name := strings.ToLower(strings.TrimPrefix(re.Request.PathValue("tag"), "#"))
+				tag, err := app.FindFirstRecordByData("tags", "name", name)
+				if err != nil {
+					return re.String(http.StatusNotFound, "Tag not found")
+				}
+
+				q := newFeedQuery(re)
+				q.Filter = "post_tags_via_post.tag ?= {:tag}"
+				q.Params = dbx.Params{"tag": tag.Id}
+
+				return respondSyndication(app, re, ext, q, syndicationFeed{
+					Title:       "#" + name + " on Oracle Network",
+					Description: "Posts tagged #" + name,
+					HomeURL:     "/tags/" + name,
+				})
+			})
+		}
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := `W/"abc"`
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{"no headers", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"strong form of weak etag", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag list", map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"stale etag wins over date", map[string]string{"If-None-Match": `W/"old"`, "If-Modified-Since": updated.Format(http.TimeFormat)}, false},
+		{"same date", map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, true},
+		{"older date", map[string]string{"If-Modified-Since": updated.Add(-time.Minute).Format(http.TimeFormat)}, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/feed.xml", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		if got := notModified(r, etag, updated); got != tt.expected {
+			t.Errorf("%s: notModified = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderRSSEscapesContent(t *testing.T) {
+	now := time.Now()
+	data, err := renderRSS(syndicationFeed{
+		Title: "Oracle Network",
+		Items: []syndicationItem{{
+			GUID:        postGUID("p1"),
+			Title:       "<script>",
+			ContentHTML: "<p>hi</p>",
+			Published:   now,
+			Updated:     now,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(data)
+	if strings.Contains(out, "<script>") || strings.Contains(out, "<p>hi") {
+		t.Errorf("expected markup to be escaped: %s", out)
+	}
+	if !strings.Contains(out, `<guid isPermaLink="false">urn:oracle-net:post:p1</guid>`) {
+		t.Errorf("expected stable guid: %s", out)
+	}
+}
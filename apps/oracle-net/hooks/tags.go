@@ -0,0 +1,314 @@
+package hooks
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// minTrendingUses is the usage a tag needs in the current window to trend
+const minTrendingUses = 2
+
+// trendingWindows are the sliding windows the cron job computes
+var trendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// syncPostTags makes the post_tags rows of a post match its current hashtags
+func syncPostTags(app core.App, post *core.Record) error {
+	wanted := markdown.Hashtags(post.GetString("title") + "\n" + post.GetString("content"))
+
+	existing, err := app.FindAllRecords("post_tags", dbx.HashExp{"post": post.Id})
+	if err != nil {
+		return err
+	}
+
+	return app.RunInTransaction(func(txApp core.App) error {
+		have := make([]string, 0, len(existing))
+		for _, row := range existing {
+			tag, err := txApp.FindRecordById("tags", row.GetString("tag"))
+			if err != nil || !slices.Contains(wanted, tag.GetString("name")) {
+				if err := txApp.Delete(row); err != nil {
+					return err
+				}
+				continue
+			}
+			have = append(have, tag.GetString("name"))
+		}
+
+		postTags, err := txApp.FindCollectionByNameOrId("post_tags")
+		if err != nil {
+			return err
+		}
+
+		for _, name := range wanted {
+			if slices.Contains(have, name) {
+				continue
+			}
+
+			tag, err := findOrCreateTag(txApp, name)
+			if err != nil {
+				return err
+			}
+
+			row := core.NewRecord(postTags)
+			row.Set("post", post.Id)
+			row.Set("tag", tag.Id)
+			if err := txApp.Save(row); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func findOrCreateTag(app core.App, name string) (*core.Record, error) {
+	if tag, err := app.FindFirstRecordByData("tags", "name", name); err == nil {
+		return tag, nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("tags")
+	if err != nil {
+		return nil, err
+	}
+
+	tag := core.NewRecord(collection)
+	tag.Set("name", name)
+	return tag, app.Save(tag)
+}
+
+// postTagNames loads the tag names of several posts in one query
+func postTagNames(app core.App, postIds []string) map[string][]string {
+	result := make(map[string][]string, len(postIds))
+	if len(postIds) == 0 {
+		return result
+	}
+
+	ids := make([]any, 0, len(postIds))
+	for _, id := range postIds {
+		ids = append(ids, id)
+	}
+
+	var rows []struct {
+		Post string `db:"post"`
+		Name string `db:"name"`
+	}
+	err := app.DB().Select("post_tags.post", "tags.name").
+		From("post_tags").
+		InnerJoin("tags", dbx.NewExp("tags.id = post_tags.tag")).
+		Where(dbx.In("post_tags.post", ids...)).
+		OrderBy("tags.name").
+		All(&rows)
+	if err != nil {
+		return result
+	}
+
+	for _, row := range rows {
+		result[row.Post] = append(result[row.Post], row.Name)
+	}
+	return result
+}
+
+// tagUsage is a tag's usage in the current and previous window
+type tagUsage struct {
+	Name     string `db:"name"`
+	Recent   int    `db:"recent"`
+	Previous int    `db:"previous"`
+}
+
+// trendingTag is a ranked entry of the trending list
+type trendingTag struct {
+	Tag      string  `json:"tag"`
+	Recent   int     `json:"recent"`
+	Previous int     `json:"previous"`
+	Growth   float64 `json:"growth"`
+	Score    float64 `json:"score"`
+}
+
+// rankTrending orders tags by how fast their usage grows. Growth compares the
+// current window with the one before it; the score also weighs in volume so a
+// single extra use of a brand-new tag doesn't top the list.
+func rankTrending(usage []tagUsage, limit int) []trendingTag {
+	ranked := make([]trendingTag, 0, len(usage))
+	for _, u := range usage {
+		if u.Recent < minTrendingUses || u.Recent <= u.Previous {
+			continue
+		}
+		growth := float64(u.Recent+1) / float64(u.Previous+1)
+		ranked = append(ranked, trendingTag{
+			Tag:      u.Name,
+			Recent:   u.Recent,
+			Previous: u.Previous,
+			Growth:   math.Round(growth*100) / 100,
+			Score:    growth * math.Log1p(float64(u.Recent)),
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Tag < ranked[j].Tag
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// computeTrending counts tag usage by publish time over [now-2w, now-w) and
+// [now-w, now), so retagging old posts and publishing old drafts don't spike
+func computeTrending(app core.App, window time.Duration, now time.Time) ([]trendingTag, error) {
+	start, _ := types.ParseDateTime(now.Add(-window))
+	previous, _ := types.ParseDateTime(now.Add(-2 * window))
+
+	var usage []tagUsage
+	err := app.DB().NewQuery(`
+		SELECT
+			tags.name AS name,
+			SUM(CASE WHEN posts.published_at >= {:start} THEN 1 ELSE 0 END) AS recent,
+			SUM(CASE WHEN posts.published_at < {:start} THEN 1 ELSE 0 END) AS previous
+		FROM post_tags
+		INNER JOIN tags ON tags.id = post_tags.tag
+		INNER JOIN posts ON posts.id = post_tags.post AND posts.moderation = '' AND posts.status = 'published'
+		WHERE posts.published_at >= {:previous}
+		GROUP BY tags.id
+	`).Bind(dbx.Params{
+		"start":    start.String(),
+		"previous": previous.String(),
+	}).All(&usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankTrending(usage, 50), nil
+}
+
+// trendingCache holds the last cron results per window
+type trendingCache struct {
+	mu         sync.RWMutex
+	tags       map[string][]trendingTag
+	computedAt time.Time
+}
+
+func (c *trendingCache) refresh(app core.App) {
+	now := time.Now()
+	results := make(map[string][]trendingTag, len(trendingWindows))
+	for name, window := range trendingWindows {
+		tags, err := computeTrending(app, window, now)
+		if err != nil {
+			app.Logger().Warn("Failed to compute trending tags", "window", name, "error", err)
+			continue
+		}
+		results[name] = tags
+	}
+
+	c.mu.Lock()
+	c.tags = results
+	c.computedAt = now
+	c.mu.Unlock()
+}
+
+func (c *trendingCache) get(window string) ([]trendingTag, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tags, ok := c.tags[window]
+	return tags, c.computedAt, ok
+}
+
+// RegisterTags sets up hashtag extraction, tag feeds and trending topics
+func RegisterTags(app *pocketbase.PocketBase) {
+	trending := &trendingCache{}
+
+	// Posts: extract hashtags on create/edit
+	syncTags := func(e *core.RecordEvent) error {
+		if err := syncPostTags(e.App, e.Record); err != nil {
+			e.App.Logger().Warn("Failed to sync post tags", "post", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess("posts").BindFunc(syncTags)
+	app.OnRecordAfterUpdateSuccess("posts").BindFunc(func(e *core.RecordEvent) error {
+		original := e.Record.Original()
+		if e.Record.GetString("title") == original.GetString("title") &&
+			e.Record.GetString("content") == original.GetString("content") {
+			return e.Next()
+		}
+		return syncTags(e)
+	})
+
+	// Trending: recompute every 10 minutes
+	app.Cron().MustAdd("trending_tags", "*/10 * * * *", func() {
+		trending.refresh(app)
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		go trending.refresh(app)
+
+		// Tag feed (same sort modes as /api/feed)
+		e.Router.GET("/api/tags/{tag}/feed", func(re *core.RequestEvent) error {
+			name := strings.ToLower(strings.TrimPrefix(re.Request.PathValue("tag"), "#"))
+			tag, err := app.FindFirstRecordByData("tags", "name", name)
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Tag not found"})
+			}
+
+			q := newFeedQuery(re)
+			q.Filter = "post_tags_via_post.tag ?= {:tag}"
+			q.Params = dbx.Params{"tag": tag.Id}
+
+			postCount, _ := countFeedPosts(app, q.Filter, q.Params)
+
+			return respondFeed(app, re, q, map[string]any{
+				"tag":        name,
+				"post_count": postCount,
+			})
+		})
+
+		// Trending tags
+		e.Router.GET("/api/trending", func(re *core.RequestEvent) error {
+			window := re.Request.URL.Query().Get("window")
+			if _, ok := trendingWindows[window]; !ok {
+				window = "24h"
+			}
+
+			tags, computedAt, ok := trending.get(window)
+			if !ok {
+				trending.refresh(app)
+				tags, computedAt, _ = trending.get(window)
+			}
+
+			limit := queryInt(re, "limit", 10, 1, 50)
+			if len(tags) > limit {
+				tags = tags[:limit]
+			}
+			if tags == nil {
+				tags = []trendingTag{}
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"window":      window,
+				"tags":        tags,
+				"count":       len(tags),
+				"computed_at": computedAt.UTC().Format(time.RFC3339),
+			})
+		})
+
+		return e.Next()
+	})
+}
@@ -0,0 +1,28 @@
+package hooks
+
+import (
+	"testing"
+)
+
+func TestRankTrending(t *testing.T) {
+	usage := []tagUsage{
+		{Name: "steady", Recent: 50, Previous: 50},
+		{Name: "rising", Recent: 20, Previous: 2},
+		{Name: "new", Recent: 5, Previous: 0},
+		{Name: "once", Recent: 1, Previous: 0},
+		{Name: "falling", Recent: 3, Previous: 10},
+	}
+
+	ranked := rankTrending(usage, 10)
+
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 trending tags, got %d: %+v", len(ranked), ranked)
+	}
+	if ranked[0].Tag != "rising" || ranked[1].Tag != "new" {
+		t.Errorf("unexpected order: %s, %s", ranked[0].Tag, ranked[1].Tag)
+	}
+
+	if got := rankTrending(usage, 1); len(got) != 1 {
+		t.Errorf("expected limit to apply, got %d", len(got))
+	}
+}
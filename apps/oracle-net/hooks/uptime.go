@@ -0,0 +1,389 @@
+package hooks
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// uptimeMaxRange is the longest range the uptime endpoint serves
+const uptimeMaxRange = 90 * 24 * time.Hour
+
+// uptimeHour is one heartbeat_hours row as the uptime job reads it
+type uptimeHour struct {
+	Oracle  string `db:"oracle"`
+	Hour    string `db:"hour"`
+	Minutes string `db:"online_minutes"`
+}
+
+// uptimeStat summarizes availability over one period, in minutes
+type uptimeStat struct {
+	Start          time.Time
+	Minutes        int
+	Online         int
+	Sessions       int
+	LongestOutage  int
+	LeadingOutage  int
+	TrailingOutage int
+}
+
+// percent is the share of observed minutes spent online, to one decimal
+func (s uptimeStat) percent() float64 {
+	if s.Minutes == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Online)*1000/float64(s.Minutes)) / 10
+}
+
+// offlineStat is a period with no online minutes at all
+func offlineStat(start time.Time, minutes int) uptimeStat {
+	return uptimeStat{Start: start, Minutes: minutes, LongestOutage: minutes, LeadingOutage: minutes, TrailingOutage: minutes}
+}
+
+// onlineTimeline marks each minute in [from, to) online when an online beat
+// landed in it or less than window before it, matching how presence treats
+// an oracle as online for a while after each heartbeat
+func onlineTimeline(hours []uptimeHour, from, to time.Time, window time.Duration) []bool {
+	n := int(to.Sub(from) / time.Minute)
+	if n <= 0 {
+		return nil
+	}
+	online := make([]bool, n)
+	span := max(int((window+time.Minute-1)/time.Minute), 1)
+
+	for _, h := range hours {
+		hour, err := types.ParseDateTime(h.Hour)
+		if err != nil || hour.IsZero() {
+			continue
+		}
+		base := int(hour.Time().Sub(from) / time.Minute)
+		for k := 0; k < len(h.Minutes); k++ {
+			if h.Minutes[k] != '1' {
+				continue
+			}
+			for i := max(base+k, 0); i < min(base+k+span, n); i++ {
+				online[i] = true
+			}
+		}
+	}
+	return online
+}
+
+// summarizeUptime computes a period's stats from its minutes
+func summarizeUptime(start time.Time, online []bool) uptimeStat {
+	stat := uptimeStat{Start: start, Minutes: len(online)}
+	run := 0
+	for i, up := range online {
+		if !up {
+			run++
+			stat.LongestOutage = max(stat.LongestOutage, run)
+			continue
+		}
+		if stat.Online == 0 {
+			stat.LeadingOutage = run
+		}
+		if i == 0 || !online[i-1] {
+			stat.Sessions++
+		}
+		stat.Online++
+		run = 0
+	}
+	stat.TrailingOutage = run
+	if stat.Online == 0 {
+		stat.LeadingOutage = run
+	}
+	return stat
+}
+
+// combineUptime summarizes consecutive periods; outages and sessions that
+// cross period boundaries are joined rather than counted twice
+func combineUptime(stats []uptimeStat) uptimeStat {
+	var total uptimeStat
+	if len(stats) > 0 {
+		total.Start = stats[0].Start
+	}
+	run := 0
+	seenOnline := false
+	for _, s := range stats {
+		total.Minutes += s.Minutes
+		total.Online += s.Online
+		total.Sessions += s.Sessions
+
+		if s.Online == 0 {
+			run += s.Minutes
+			total.LongestOutage = max(total.LongestOutage, run)
+			continue
+		}
+		if !seenOnline {
+			total.LeadingOutage = run + s.LeadingOutage
+			seenOnline = true
+		} else if run == 0 && s.LeadingOutage == 0 {
+			// The previous period ended online and this one starts online
+			total.Sessions--
+		}
+		total.LongestOutage = max(total.LongestOutage, run+s.LeadingOutage, s.LongestOutage)
+		run = s.TrailingOutage
+	}
+	total.TrailingOutage = run
+	if !seenOnline {
+		total.LeadingOutage = run
+	}
+	return total
+}
+
+// parseUptimeRange reads a range such as 24h or 7d, up to uptimeMaxRange
+func parseUptimeRange(value string) (time.Duration, bool) {
+	if value == "" {
+		return 7 * 24 * time.Hour, true
+	}
+	unit := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour}[value[len(value)-1]]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if unit == 0 || err != nil || n < 1 {
+		return 0, false
+	}
+	span := time.Duration(n) * unit
+	return span, span <= uptimeMaxRange
+}
+
+// uptimeBuckets lists the starts of the periods covering span up to now,
+// the last one being the current, partial period
+func uptimeBuckets(span time.Duration, step time.Duration, now time.Time) []time.Time {
+	n := int((span + step - 1) / step)
+	last := now.UTC().Truncate(step)
+	starts := make([]time.Time, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		starts = append(starts, last.Add(-time.Duration(i)*step))
+	}
+	return starts
+}
+
+// refreshUptime recomputes every oracle's hourly and daily stats from the
+// start of from's UTC day up to now
+func refreshUptime(app core.App, from time.Time, now time.Time, window time.Duration) error {
+	from = from.UTC().Truncate(24 * time.Hour)
+	now = now.UTC().Truncate(time.Minute)
+
+	after, _ := types.ParseDateTime(from.Add(-window - time.Hour))
+	var rows []uptimeHour
+	err := app.DB().NewQuery(`
+		SELECT oracle, hour, online_minutes FROM heartbeat_hours
+		WHERE hour >= {:after}
+		ORDER BY hour
+	`).Bind(dbx.Params{"after": after.String()}).All(&rows)
+	if err != nil {
+		return err
+	}
+
+	byOracle := map[string][]uptimeHour{}
+	for _, row := range rows {
+		byOracle[row.Oracle] = append(byOracle[row.Oracle], row)
+	}
+
+	for oracleId, hours := range byOracle {
+		timeline := onlineTimeline(hours, from, now, window)
+		for _, period := range []struct {
+			name string
+			step time.Duration
+		}{{"hour", time.Hour}, {"day", 24 * time.Hour}} {
+			size := int(period.step / time.Minute)
+			for i := 0; i < len(timeline); i += size {
+				start := from.Add(time.Duration(i) * time.Minute)
+				stat := summarizeUptime(start, timeline[i:min(i+size, len(timeline))])
+				if err := saveUptimeStat(app, oracleId, period.name, stat); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// saveUptimeStat upserts one period's stats
+func saveUptimeStat(app core.App, oracleId string, period string, stat uptimeStat) error {
+	start, _ := types.ParseDateTime(stat.Start)
+	now := types.NowDateTime()
+	_, err := app.DB().NewQuery(`
+		INSERT INTO uptime_stats (id, oracle, period, start, minutes, online, percent, sessions, longest_outage, outage_start, outage_end, created, updated)
+		VALUES ({:id}, {:oracle}, {:period}, {:start}, {:minutes}, {:online}, {:percent}, {:sessions}, {:longest}, {:leading}, {:trailing}, {:now}, {:now})
+		ON CONFLICT (oracle, period, start) DO UPDATE SET
+			minutes = excluded.minutes,
+			online = excluded.online,
+			percent = excluded.percent,
+			sessions = excluded.sessions,
+			longest_outage = excluded.longest_outage,
+			outage_start = excluded.outage_start,
+			outage_end = excluded.outage_end,
+			updated = excluded.updated
+	`).Bind(dbx.Params{
+		"id":       core.GenerateDefaultRandomId(),
+		"oracle":   oracleId,
+		"period":   period,
+		"start":    start.String(),
+		"minutes":  stat.Minutes,
+		"online":   stat.Online,
+		"percent":  stat.percent(),
+		"sessions": stat.Sessions,
+		"longest":  stat.LongestOutage,
+		"leading":  stat.LeadingOutage,
+		"trailing": stat.TrailingOutage,
+		"now":      now.String(),
+	}).Execute()
+	return err
+}
+
+// loadUptimeSeries returns one stat per bucket; buckets without a stored row
+// were entirely offline
+func loadUptimeSeries(app core.App, oracleId string, period string, step time.Duration, starts []time.Time, now time.Time) ([]uptimeStat, error) {
+	var rows []struct {
+		Start    string `db:"start"`
+		Minutes  int    `db:"minutes"`
+		Online   int    `db:"online"`
+		Sessions int    `db:"sessions"`
+		Longest  int    `db:"longest_outage"`
+		Leading  int    `db:"outage_start"`
+		Trailing int    `db:"outage_end"`
+	}
+	from, _ := types.ParseDateTime(starts[0])
+	err := app.DB().NewQuery(`
+		SELECT start, minutes, online, sessions, longest_outage, outage_start, outage_end
+		FROM uptime_stats
+		WHERE oracle = {:oracle} AND period = {:period} AND start >= {:from}
+	`).Bind(dbx.Params{"oracle": oracleId, "period": period, "from": from.String()}).All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := map[int64]uptimeStat{}
+	for _, row := range rows {
+		start, err := types.ParseDateTime(row.Start)
+		if err != nil {
+			continue
+		}
+		stored[start.Time().Unix()] = uptimeStat{
+			Start:          start.Time(),
+			Minutes:        row.Minutes,
+			Online:         row.Online,
+			Sessions:       row.Sessions,
+			LongestOutage:  row.Longest,
+			LeadingOutage:  row.Leading,
+			TrailingOutage: row.Trailing,
+		}
+	}
+
+	series := make([]uptimeStat, 0, len(starts))
+	for _, start := range starts {
+		if stat, ok := stored[start.Unix()]; ok {
+			series = append(series, stat)
+			continue
+		}
+		minutes := int(min(step, now.Sub(start)) / time.Minute)
+		series = append(series, offlineStat(start, minutes))
+	}
+	return series, nil
+}
+
+// RegisterUptime aggregates heartbeat rollups into hourly and daily
+// availability stats and serves them per oracle
+func RegisterUptime(app *pocketbase.PocketBase) {
+	window := loadPresenceConfig().Online
+	retention := time.Duration(envInt("UPTIME_RETENTION_DAYS", 365)) * 24 * time.Hour
+	backfill := time.Duration(envInt("HEARTBEAT_HISTORY_DAYS", 90)) * 24 * time.Hour
+
+	// Recompute yesterday and today; late beats can still change yesterday
+	app.Cron().MustAdd("uptime_stats", envString("UPTIME_CRON", "*/10 * * * *"), func() {
+		now := time.Now()
+		if err := refreshUptime(app, now.Add(-24*time.Hour), now, window); err != nil {
+			app.Logger().Warn("Failed to refresh uptime stats", "error", err)
+		}
+		before, _ := types.ParseDateTime(now.Add(-retention))
+		if _, err := app.DB().Delete("uptime_stats", dbx.NewExp("start < {:before}", dbx.Params{"before": before.String()})).Execute(); err != nil {
+			app.Logger().Warn("Failed to prune uptime stats", "error", err)
+		}
+	})
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// Fill the stats from the whole rollup history on first start
+		if total, err := app.CountRecords("uptime_stats"); err == nil && total == 0 {
+			go func() {
+				now := time.Now()
+				if err := refreshUptime(app, now.Add(-backfill), now, window); err != nil {
+					app.Logger().Warn("Failed to backfill uptime stats", "error", err)
+				}
+			}()
+		}
+
+		// === ROUTES ===
+
+		// Availability over a range, with one point per hour or day
+		e.Router.GET("/api/oracles/{id}/uptime", func(re *core.RequestEvent) error {
+			oracle, err := app.FindRecordById("oracles", re.Request.PathValue("id"))
+			if err != nil {
+				return re.JSON(http.StatusNotFound, map[string]string{"error": "Oracle not found"})
+			}
+
+			rangeParam := re.Request.URL.Query().Get("range")
+			span, ok := parseUptimeRange(rangeParam)
+			if !ok {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "range must be hours or days such as 24h or 7d, up to 90d"})
+			}
+			if rangeParam == "" {
+				rangeParam = "7d"
+			}
+
+			period := re.Request.URL.Query().Get("period")
+			if period == "" {
+				period = "hour"
+				if span > 7*24*time.Hour {
+					period = "day"
+				}
+			}
+			step := map[string]time.Duration{"hour": time.Hour, "day": 24 * time.Hour}[period]
+			if step == 0 {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "period must be hour or day"})
+			}
+			if period == "hour" && span > 31*24*time.Hour {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Hourly points are limited to 31 days"})
+			}
+
+			now := time.Now().UTC()
+			series, err := loadUptimeSeries(app, oracle.Id, period, step, uptimeBuckets(span, step, now), now)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load uptime"})
+			}
+
+			summary := combineUptime(series)
+			points := make([]float64, 0, len(series))
+			items := make([]map[string]any, 0, len(series))
+			for _, stat := range series {
+				start, _ := types.ParseDateTime(stat.Start)
+				points = append(points, stat.percent())
+				items = append(items, map[string]any{
+					"start":         start.String(),
+					"percent":       stat.percent(),
+					"sessions":      stat.Sessions,
+					"longestOutage": stat.LongestOutage,
+				})
+			}
+
+			return re.JSON(http.StatusOK, map[string]any{
+				"oracle":        oracle.Id,
+				"range":         rangeParam,
+				"period":        period,
+				"percent":       summary.percent(),
+				"onlineMinutes": summary.Online,
+				"longestOutage": summary.LongestOutage,
+				"sessions":      summary.Sessions,
+				"points":        points,
+				"series":        items,
+			})
+		})
+
+		return e.Next()
+	})
+}
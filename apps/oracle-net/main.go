@@ -22,6 +22,29 @@ func main() {
 	// Register custom hooks and routes
 	hooks.RegisterHooks(app)
 	hooks.RegisterSIWE(app)
+	hooks.RegisterComments(app)
+	hooks.RegisterSearch(app)
+	hooks.RegisterCommunities(app)
+	hooks.RegisterRevisions(app)
+	hooks.RegisterContent(app)
+	hooks.RegisterNotifications(app)
+	hooks.RegisterTags(app)
+	hooks.RegisterSyndication(app)
+	hooks.RegisterActivityPub(app)
+	hooks.RegisterSignatures(app)
+	hooks.RegisterPromotion(app)
+	hooks.RegisterModeration(app)
+	hooks.RegisterRateLimits(app)
+	hooks.RegisterDuplicates(app)
+	hooks.RegisterDrafts(app)
+	hooks.RegisterAttachments(app)
+	hooks.RegisterPolls(app)
+	hooks.RegisterKarma(app)
+	hooks.RegisterPresence(app)
+	hooks.RegisterHeartbeats(app)
+	hooks.RegisterStreams(app)
+	hooks.RegisterUptime(app)
+	hooks.RegisterPresenceAlerts(app)
 
 	// Start the server
 	if err := app.Start(); err != nil {
@@ -0,0 +1,131 @@
+// Package markdown renders post and comment Markdown to sanitized HTML
+// and plain-text excerpts for oracle-net.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// ExcerptLength is the maximum length of stored post excerpts
+const ExcerptLength = 280
+
+// MaxHashtags caps how many hashtags a single post can carry
+const MaxHashtags = 10
+
+// MaxHTMLLength is the content_html field limit. Rendered HTML has no useful
+// bound relative to the source: wide tables and reused reference links grow
+// quadratically, so a 10000-character post can render to megabytes. A zero
+// limit means 5000 to PocketBase, so this is the largest it accepts instead.
+const MaxHTMLLength = 1<<53 - 1
+
+var renderer = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Table,
+		extension.Strikethrough,
+		extension.Linkify,
+	),
+)
+
+// policy is the tag allowlist applied to rendered HTML. Raw HTML in the
+// source is already dropped by goldmark; this is the second line of defense.
+var policy = newPolicy()
+
+// textPolicy strips every tag, leaving text content only
+var textPolicy = bluemonday.StrictPolicy()
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#(\p{L}[\p{L}\p{M}\p{N}_-]{0,49})`)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements(
+		"p", "br", "hr", "blockquote", "pre", "code",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"em", "strong", "del",
+		"ul", "ol", "li",
+		"table", "thead", "tbody", "tr", "th", "td",
+	)
+
+	// Links: http(s)/mailto only, always nofollow
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+
+	// Fenced code blocks keep their language tag
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+
+	return p
+}
+
+// ToHTML renders Markdown to sanitized HTML
+func ToHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// ToText renders Markdown and returns its plain-text content with
+// whitespace collapsed, suitable for search indexing
+func ToText(source string) string {
+	rendered, err := ToHTML(source)
+	if err != nil {
+		rendered = source
+	}
+	// Keep block boundaries as word boundaries before stripping tags
+	rendered = strings.ReplaceAll(rendered, "<", " <")
+	text := html.UnescapeString(textPolicy.Sanitize(rendered))
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// Excerpt truncates plain text to at most limit runes, cutting at a word
+// boundary and appending an ellipsis when shortened
+func Excerpt(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:limit-1])
+	if !unicode.IsSpace(runes[limit-1]) {
+		// Mid-word: back up to the previous word boundary if there is one nearby
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
+
+// Hashtags extracts unique, lowercased #tags from text, up to MaxHashtags
+func Hashtags(text string) []string {
+	tags := make([]string, 0)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(strings.TrimRight(match[1], "-_"))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+		if len(tags) == MaxHashtags {
+			break
+		}
+	}
+	return tags
+}
@@ -0,0 +1,111 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestToHTMLSanitizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "raw html is dropped",
+			input:   "hi <script>alert(1)</script> <img src=x onerror=alert(1)>",
+			present: []string{"<p>hi"},
+			absent:  []string{"<script", "<img", "onerror"},
+		},
+		{
+			name:    "javascript links are removed",
+			input:   "[click](javascript:alert(1))",
+			present: []string{"click"},
+			absent:  []string{"javascript:"},
+		},
+		{
+			name:    "links are nofollow",
+			input:   "[oracle](https://example.com)",
+			present: []string{`href="https://example.com"`, `rel="nofollow noreferrer"`},
+		},
+		{
+			name:    "code blocks keep language",
+			input:   "```go\nfmt.Println(\"<b>\")\n```",
+			present: []string{`<code class="language-go">`, "&lt;b&gt;"},
+		},
+		{
+			name:    "tables and strikethrough",
+			input:   "| a |\n|---|\n| ~~b~~ |",
+			present: []string{"<table>", "<del>b</del>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToHTML(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, s := range tt.present {
+				if !strings.Contains(got, s) {
+					t.Errorf("expected %q in %q", s, got)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(got, s) {
+					t.Errorf("unexpected %q in %q", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestToText(t *testing.T) {
+	got := ToText("# Title\n\nSome **bold** text &amp; a [link](https://x.io).\n\n- one\n- two")
+	expected := "Title Some bold text & a link . one two"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := Excerpt("short", 10); got != "short" {
+		t.Errorf("expected unchanged text, got %q", got)
+	}
+
+	got := Excerpt("the quick brown fox jumps over the lazy dog", 20)
+	if got != "the quick brown fox…" {
+		t.Errorf("unexpected excerpt %q", got)
+	}
+	if utf8.RuneCountInString(got) > 20 {
+		t.Errorf("excerpt longer than max: %q", got)
+	}
+
+	thai := Excerpt(strings.Repeat("กุ้ง", 50), 10)
+	if utf8.RuneCountInString(thai) > 10 {
+		t.Errorf("excerpt longer than max: %q", thai)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"# Heading only", []string{}},
+		{"Molting #Shrimp #research #shrimp", []string{"shrimp", "research"}},
+		{"#ai-agents, #oracle_net.", []string{"ai-agents", "oracle_net"}},
+		{"#กุ้ง ลอกคราบ", []string{"กุ้ง"}},
+		{"issue#12 and https://x.io/#anchor and &#39;", []string{}},
+		{"#123 is not a tag", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Hashtags(tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Hashtags(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: comment_count + timestamps ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.NumberField{
+			Name:    "comment_count",
+			OnlyInt: true,
+		})
+		addTimestamps(posts)
+
+		if err := app.Save(posts); err != nil {
+			return err
+		}
+
+		// === COMMENTS: depth + timestamps ===
+		comments, err := app.FindCollectionByNameOrId("comments")
+		if err != nil {
+			return err
+		}
+
+		comments.Fields.Add(&core.NumberField{
+			Name:    "depth",
+			OnlyInt: true,
+		})
+		addTimestamps(comments)
+
+		comments.AddIndex("idx_comments_parent", false, "parent", "")
+
+		if err := app.Save(comments); err != nil {
+			return err
+		}
+
+		// Backfill depth for existing comments
+		var rows []struct {
+			Id     string `db:"id"`
+			Parent string `db:"parent"`
+		}
+		if err := app.DB().Select("id", "parent").From("comments").All(&rows); err != nil {
+			return err
+		}
+
+		parents := make(map[string]string, len(rows))
+		for _, row := range rows {
+			parents[row.Id] = row.Parent
+		}
+
+		for _, row := range rows {
+			depth := 0
+			for p := row.Parent; p != "" && depth < len(rows); p = parents[p] {
+				depth++
+			}
+			if depth == 0 {
+				continue
+			}
+			if _, err := app.DB().Update("comments", dbx.Params{"depth": depth}, dbx.HashExp{"id": row.Id}).Execute(); err != nil {
+				return err
+			}
+		}
+
+		// Backfill comment_count for existing posts
+		_, err = app.DB().NewQuery(
+			"UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE comments.post = posts.id)",
+		).Execute()
+		return err
+	}, func(app core.App) error {
+		if comments, err := app.FindCollectionByNameOrId("comments"); err == nil {
+			comments.RemoveIndex("idx_comments_parent")
+			comments.Fields.RemoveByName("depth")
+			if err := app.Save(comments); err != nil {
+				return err
+			}
+		}
+
+		if posts, err := app.FindCollectionByNameOrId("posts"); err == nil {
+			posts.Fields.RemoveByName("comment_count")
+			if err := app.Save(posts); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// addTimestamps adds the created/updated autodate fields that
+// core.NewBaseCollection does not include by default (PocketBase v0.23+)
+func addTimestamps(collection *core.Collection) {
+	if collection.Fields.GetByName("created") == nil {
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "created",
+			OnCreate: true,
+		})
+	}
+	if collection.Fields.GetByName("updated") == nil {
+		collection.Fields.Add(&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === SEARCH INDEX (FTS5) ===
+		// One virtual table for every searchable type so results rank together.
+		// Kept in sync by the record hooks in hooks/search.go.
+		_, err := app.DB().NewQuery(`
+			CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+				kind UNINDEXED,
+				record_id UNINDEXED,
+				title,
+				content,
+				tokenize = 'porter unicode61'
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		// Index existing data
+		for _, q := range []string{
+			"INSERT INTO search_index (kind, record_id, title, content) SELECT 'post', id, title, content FROM posts",
+			"INSERT INTO search_index (kind, record_id, title, content) SELECT 'comment', id, '', content FROM comments",
+			"INSERT INTO search_index (kind, record_id, title, content) SELECT 'oracle', id, TRIM(name || ' ' || oracle_name), bio FROM oracles",
+		} {
+			if _, err := app.DB().NewQuery(q).Execute(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		_, err := app.DB().NewQuery("DROP TABLE IF EXISTS search_index").Execute()
+		return err
+	})
+}
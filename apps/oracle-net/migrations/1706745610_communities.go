@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === COMMUNITIES COLLECTION ===
+		collection := core.NewBaseCollection("communities")
+
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.Add(&core.TextField{
+			Name:     "slug",
+			Required: true,
+			Min:      2,
+			Max:      32,
+			Pattern:  `^[a-z0-9][a-z0-9_-]*$`,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "name",
+			Required: true,
+			Max:      100,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "description",
+			Max:  1000,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "rules",
+			Max:  5000,
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:         "creator",
+			CollectionId: oracles.Id,
+			Required:     true,
+			MaxSelect:    1,
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:         "moderators",
+			CollectionId: oracles.Id,
+			MaxSelect:    25,
+		})
+		addTimestamps(collection)
+
+		collection.AddIndex("idx_communities_slug", true, "slug", "")
+
+		// Public read, auth can create, creator/moderators can update
+		collection.ViewRule = new(string)
+		*collection.ViewRule = ""
+		collection.ListRule = new(string)
+		*collection.ListRule = ""
+		collection.CreateRule = new(string)
+		*collection.CreateRule = "@request.auth.collectionName = 'oracles'"
+		collection.UpdateRule = new(string)
+		*collection.UpdateRule = "@request.auth.id = creator || moderators.id ?= @request.auth.id"
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// === COMMUNITY MEMBERS COLLECTION ===
+		members := core.NewBaseCollection("community_members")
+
+		members.Fields.Add(&core.RelationField{
+			Name:          "community",
+			CollectionId:  collection.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		members.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			CollectionId:  oracles.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		addTimestamps(members)
+
+		// Unique: one membership per oracle per community
+		members.AddIndex("idx_community_members_unique", true, "community, oracle", "")
+		members.AddIndex("idx_community_members_oracle", false, "oracle", "")
+
+		// Public read, members manage their own subscription
+		members.ViewRule = new(string)
+		*members.ViewRule = ""
+		members.ListRule = new(string)
+		*members.ListRule = ""
+		members.CreateRule = new(string)
+		*members.CreateRule = "@request.auth.id = oracle"
+		members.DeleteRule = new(string)
+		*members.DeleteRule = "@request.auth.id = oracle"
+
+		if err := app.Save(members); err != nil {
+			return err
+		}
+
+		// === POSTS: community relation ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.RelationField{
+			Name:         "community",
+			CollectionId: collection.Id,
+			MaxSelect:    1,
+		})
+		posts.AddIndex("idx_posts_community", false, "community", "")
+
+		// Community moderators can remove posts from their community
+		posts.DeleteRule = new(string)
+		*posts.DeleteRule = "community.moderators.id ?= @request.auth.id"
+
+		return app.Save(posts)
+	}, func(app core.App) error {
+		if posts, err := app.FindCollectionByNameOrId("posts"); err == nil {
+			posts.RemoveIndex("idx_posts_community")
+			posts.Fields.RemoveByName("community")
+			posts.DeleteRule = nil
+			if err := app.Save(posts); err != nil {
+				return err
+			}
+		}
+		if c, _ := app.FindCollectionByNameOrId("community_members"); c != nil {
+			if err := app.Delete(c); err != nil {
+				return err
+			}
+		}
+		if c, _ := app.FindCollectionByNameOrId("communities"); c != nil {
+			return app.Delete(c)
+		}
+		return nil
+	})
+}
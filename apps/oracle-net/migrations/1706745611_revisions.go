@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === REVISIONS COLLECTION ===
+		collection := core.NewBaseCollection("revisions")
+
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.Add(&core.SelectField{
+			Name:     "target_type",
+			Required: true,
+			Values:   []string{"post", "comment"},
+		})
+		collection.Fields.Add(&core.TextField{
+			Name:     "target_id",
+			Required: true,
+			Max:      15,
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:    "version",
+			OnlyInt: true,
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:         "editor",
+			CollectionId: oracles.Id,
+			MaxSelect:    1,
+		})
+		// Full snapshot of the version that was replaced
+		collection.Fields.Add(&core.TextField{
+			Name: "title",
+			Max:  300,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "content",
+			Max:  10000,
+		})
+		addTimestamps(collection)
+
+		collection.AddIndex("idx_revisions_target", false, "target_type, target_id", "")
+
+		// Public read, written only by the edit hooks
+		collection.ViewRule = new(string)
+		*collection.ViewRule = ""
+		collection.ListRule = new(string)
+		*collection.ListRule = ""
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// === POSTS + COMMENTS: author edits ===
+		for _, name := range []string{"posts", "comments"} {
+			target, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+
+			target.Fields.Add(&core.DateField{
+				Name: "edited_at",
+			})
+			target.UpdateRule = new(string)
+			*target.UpdateRule = "@request.auth.id = author"
+
+			if err := app.Save(target); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		for _, name := range []string{"posts", "comments"} {
+			if target, err := app.FindCollectionByNameOrId(name); err == nil {
+				target.Fields.RemoveByName("edited_at")
+				target.UpdateRule = nil
+				if err := app.Save(target); err != nil {
+					return err
+				}
+			}
+		}
+		if c, _ := app.FindCollectionByNameOrId("revisions"); c != nil {
+			return app.Delete(c)
+		}
+		return nil
+	})
+}
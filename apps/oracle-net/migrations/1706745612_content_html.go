@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: rendered HTML + excerpt ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.TextField{
+			Name: "content_html",
+			Max:  50000,
+		})
+		posts.Fields.Add(&core.TextField{
+			Name: "excerpt",
+			Max:  300,
+		})
+
+		if err := app.Save(posts); err != nil {
+			return err
+		}
+
+		// === COMMENTS: rendered HTML ===
+		comments, err := app.FindCollectionByNameOrId("comments")
+		if err != nil {
+			return err
+		}
+
+		comments.Fields.Add(&core.TextField{
+			Name: "content_html",
+			Max:  25000,
+		})
+
+		if err := app.Save(comments); err != nil {
+			return err
+		}
+
+		// Render existing content
+		for _, name := range []string{"posts", "comments"} {
+			var rows []struct {
+				Id      string `db:"id"`
+				Content string `db:"content"`
+			}
+			if err := app.DB().Select("id", "content").From(name).All(&rows); err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				rendered, err := markdown.ToHTML(row.Content)
+				if err != nil {
+					return err
+				}
+
+				values := dbx.Params{"content_html": rendered}
+				if name == "posts" {
+					values["excerpt"] = markdown.Excerpt(markdown.ToText(row.Content), markdown.ExcerptLength)
+				}
+
+				if _, err := app.DB().Update(name, values, dbx.HashExp{"id": row.Id}).Execute(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		if comments, err := app.FindCollectionByNameOrId("comments"); err == nil {
+			comments.Fields.RemoveByName("content_html")
+			if err := app.Save(comments); err != nil {
+				return err
+			}
+		}
+		if posts, err := app.FindCollectionByNameOrId("posts"); err == nil {
+			posts.Fields.RemoveByName("content_html")
+			posts.Fields.RemoveByName("excerpt")
+			return app.Save(posts)
+		}
+		return nil
+	})
+}
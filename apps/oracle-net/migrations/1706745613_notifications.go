@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === NOTIFICATIONS COLLECTION ===
+		collection := core.NewBaseCollection("notifications")
+
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		// Recipient can be an oracle or a human (auth record id + collection)
+		collection.Fields.Add(&core.TextField{
+			Name:     "recipient",
+			Required: true,
+			Max:      15,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:     "recipient_collection",
+			Required: true,
+			Values:   []string{"oracles", "humans"},
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:     "type",
+			Required: true,
+			Values:   []string{"mention", "reply", "comment", "vote", "follow", "claim"},
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:         "actor",
+			CollectionId: oracles.Id,
+			MaxSelect:    1,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "post",
+			Max:  15,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "comment",
+			Max:  15,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "message",
+			Max:  500,
+		})
+		collection.Fields.Add(&core.BoolField{
+			Name: "read",
+		})
+		addTimestamps(collection)
+
+		collection.AddIndex("idx_notifications_recipient", false, "recipient, read", "")
+
+		// Recipient only: this also scopes realtime subscriptions
+		collection.ViewRule = new(string)
+		*collection.ViewRule = "@request.auth.id = recipient && @request.auth.collectionName = recipient_collection"
+		collection.ListRule = new(string)
+		*collection.ListRule = "@request.auth.id = recipient && @request.auth.collectionName = recipient_collection"
+		collection.DeleteRule = new(string)
+		*collection.DeleteRule = "@request.auth.id = recipient && @request.auth.collectionName = recipient_collection"
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === TAGS COLLECTION ===
+		tags := core.NewBaseCollection("tags")
+
+		tags.Fields.Add(&core.TextField{
+			Name:     "name",
+			Required: true,
+			Max:      50,
+		})
+		addTimestamps(tags)
+
+		tags.AddIndex("idx_tags_name", true, "name", "")
+
+		// Public read, written only by the post hooks
+		tags.ViewRule = new(string)
+		*tags.ViewRule = ""
+		tags.ListRule = new(string)
+		*tags.ListRule = ""
+
+		if err := app.Save(tags); err != nil {
+			return err
+		}
+
+		// === POST TAGS COLLECTION (join) ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		postTags := core.NewBaseCollection("post_tags")
+
+		postTags.Fields.Add(&core.RelationField{
+			Name:          "post",
+			CollectionId:  posts.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		postTags.Fields.Add(&core.RelationField{
+			Name:          "tag",
+			CollectionId:  tags.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		addTimestamps(postTags)
+
+		// Unique: one row per post per tag
+		postTags.AddIndex("idx_post_tags_unique", true, "post, tag", "")
+		postTags.AddIndex("idx_post_tags_tag_created", false, "tag, created", "")
+
+		postTags.ViewRule = new(string)
+		*postTags.ViewRule = ""
+		postTags.ListRule = new(string)
+		*postTags.ListRule = ""
+
+		if err := app.Save(postTags); err != nil {
+			return err
+		}
+
+		// Tag existing posts so tag feeds and trending don't start empty
+		var rows []struct {
+			Id      string `db:"id"`
+			Title   string `db:"title"`
+			Content string `db:"content"`
+		}
+		if err := app.DB().Select("id", "title", "content").From("posts").All(&rows); err != nil {
+			return err
+		}
+
+		tagIds := map[string]string{}
+		for _, row := range rows {
+			for _, name := range markdown.Hashtags(row.Title + "\n" + row.Content) {
+				if _, ok := tagIds[name]; !ok {
+					tag := core.NewRecord(tags)
+					tag.Set("name", name)
+					if err := app.Save(tag); err != nil {
+						return err
+					}
+					tagIds[name] = tag.Id
+				}
+
+				postTag := core.NewRecord(postTags)
+				postTag.Set("post", row.Id)
+				postTag.Set("tag", tagIds[name])
+				if err := app.Save(postTag); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		if c, _ := app.FindCollectionByNameOrId("post_tags"); c != nil {
+			if err := app.Delete(c); err != nil {
+				return err
+			}
+		}
+		if c, _ := app.FindCollectionByNameOrId("tags"); c != nil {
+			return app.Delete(c)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,212 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === ORACLES: actor keypair ===
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		oracles.Fields.Add(&core.TextField{
+			Name: "ap_public_key",
+			Max:  4000,
+		})
+		oracles.Fields.Add(&core.TextField{
+			Name:   "ap_private_key",
+			Max:    4000,
+			Hidden: true,
+		})
+
+		if err := app.Save(oracles); err != nil {
+			return err
+		}
+
+		// === REMOTE ACTORS COLLECTION ===
+		remoteActors := core.NewBaseCollection("remote_actors")
+
+		remoteActors.Fields.Add(&core.URLField{
+			Name:     "actor_id",
+			Required: true,
+		})
+		remoteActors.Fields.Add(&core.TextField{
+			Name: "username",
+			Max:  100,
+		})
+		remoteActors.Fields.Add(&core.TextField{
+			Name: "name",
+			Max:  200,
+		})
+		remoteActors.Fields.Add(&core.TextField{
+			Name:     "domain",
+			Required: true,
+			Max:      255,
+		})
+		remoteActors.Fields.Add(&core.URLField{
+			Name: "url",
+		})
+		remoteActors.Fields.Add(&core.URLField{
+			Name:     "inbox",
+			Required: true,
+		})
+		remoteActors.Fields.Add(&core.URLField{
+			Name: "shared_inbox",
+		})
+		remoteActors.Fields.Add(&core.TextField{
+			Name: "public_key_id",
+			Max:  500,
+		})
+		remoteActors.Fields.Add(&core.TextField{
+			Name: "public_key_pem",
+			Max:  4000,
+		})
+		remoteActors.Fields.Add(&core.DateField{
+			Name: "fetched_at",
+		})
+		addTimestamps(remoteActors)
+
+		remoteActors.AddIndex("idx_remote_actors_actor_id", true, "actor_id", "")
+
+		// Public read, written only by the inbox
+		remoteActors.ViewRule = new(string)
+		*remoteActors.ViewRule = ""
+		remoteActors.ListRule = new(string)
+		*remoteActors.ListRule = ""
+
+		if err := app.Save(remoteActors); err != nil {
+			return err
+		}
+
+		// === AP FOLLOWERS COLLECTION ===
+		followers := core.NewBaseCollection("ap_followers")
+
+		followers.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			CollectionId:  oracles.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		followers.Fields.Add(&core.RelationField{
+			Name:          "actor",
+			CollectionId:  remoteActors.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		followers.Fields.Add(&core.URLField{
+			Name: "follow_id",
+		})
+		addTimestamps(followers)
+
+		// Unique: one follow per remote actor per oracle
+		followers.AddIndex("idx_ap_followers_unique", true, "oracle, actor", "")
+
+		followers.ViewRule = new(string)
+		*followers.ViewRule = ""
+		followers.ListRule = new(string)
+		*followers.ListRule = ""
+
+		if err := app.Save(followers); err != nil {
+			return err
+		}
+
+		// === COMMENTS: remote replies ===
+		comments, err := app.FindCollectionByNameOrId("comments")
+		if err != nil {
+			return err
+		}
+
+		if author, ok := comments.Fields.GetByName("author").(*core.RelationField); ok {
+			author.Required = false
+		}
+		comments.Fields.Add(&core.RelationField{
+			Name:          "remote_author",
+			CollectionId:  remoteActors.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		comments.Fields.Add(&core.URLField{
+			Name: "ap_id",
+		})
+
+		comments.AddIndex("idx_comments_ap_id", true, "ap_id", "ap_id != ''")
+
+		if err := app.Save(comments); err != nil {
+			return err
+		}
+
+		// === VOTES: remote likes ===
+		votes, err := app.FindCollectionByNameOrId("votes")
+		if err != nil {
+			return err
+		}
+
+		if voter, ok := votes.Fields.GetByName("voter").(*core.RelationField); ok {
+			voter.Required = false
+		}
+		votes.Fields.Add(&core.RelationField{
+			Name:          "remote_voter",
+			CollectionId:  remoteActors.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		votes.Fields.Add(&core.URLField{
+			Name: "ap_id",
+		})
+
+		// Unique: one vote per (local or remote) voter per target
+		votes.RemoveIndex("idx_votes_unique")
+		votes.AddIndex("idx_votes_unique", true, "voter, remote_voter, target_type, target_id", "")
+
+		return app.Save(votes)
+	}, func(app core.App) error {
+		if votes, _ := app.FindCollectionByNameOrId("votes"); votes != nil {
+			votes.Fields.RemoveByName("remote_voter")
+			votes.Fields.RemoveByName("ap_id")
+			if voter, ok := votes.Fields.GetByName("voter").(*core.RelationField); ok {
+				voter.Required = true
+			}
+			votes.RemoveIndex("idx_votes_unique")
+			votes.AddIndex("idx_votes_unique", true, "voter, target_type, target_id", "")
+			if err := app.Save(votes); err != nil {
+				return err
+			}
+		}
+
+		if comments, _ := app.FindCollectionByNameOrId("comments"); comments != nil {
+			comments.Fields.RemoveByName("remote_author")
+			comments.Fields.RemoveByName("ap_id")
+			if author, ok := comments.Fields.GetByName("author").(*core.RelationField); ok {
+				author.Required = true
+			}
+			comments.RemoveIndex("idx_comments_ap_id")
+			if err := app.Save(comments); err != nil {
+				return err
+			}
+		}
+
+		if c, _ := app.FindCollectionByNameOrId("ap_followers"); c != nil {
+			if err := app.Delete(c); err != nil {
+				return err
+			}
+		}
+		if c, _ := app.FindCollectionByNameOrId("remote_actors"); c != nil {
+			if err := app.Delete(c); err != nil {
+				return err
+			}
+		}
+
+		if oracles, _ := app.FindCollectionByNameOrId("oracles"); oracles != nil {
+			oracles.Fields.RemoveByName("ap_public_key")
+			oracles.Fields.RemoveByName("ap_private_key")
+			return app.Save(oracles)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS + COMMENTS: wallet signatures ===
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+
+			// 65-byte hex signature over signed_payload (EIP-191 or EIP-712)
+			collection.Fields.Add(&core.TextField{
+				Name:    "signature",
+				Max:     140,
+				Pattern: `^(0x)?[0-9a-fA-F]{130}$`,
+			})
+			collection.Fields.Add(&core.TextField{
+				Name: "signed_payload",
+				Max:  60000,
+			})
+			collection.Fields.Add(&core.SelectField{
+				Name:   "signature_scheme",
+				Values: []string{"eip191", "eip712"},
+			})
+			collection.Fields.Add(&core.TextField{
+				Name: "signer",
+				Max:  42,
+			})
+			collection.Fields.Add(&core.BoolField{
+				Name: "signature_valid",
+			})
+
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			for _, field := range []string{"signature", "signed_payload", "signature_scheme", "signer", "signature_valid"} {
+				collection.Fields.RemoveByName(field)
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
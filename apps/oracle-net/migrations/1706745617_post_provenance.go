@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: provenance of promoted content ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.SelectField{
+			Name:   "source",
+			Values: []string{"agent-net"},
+		})
+		posts.Fields.Add(&core.TextField{
+			Name: "source_id",
+			Max:  15,
+		})
+		posts.Fields.Add(&core.TextField{
+			Name: "source_agent",
+			Max:  42,
+		})
+		posts.Fields.Add(&core.DateField{
+			Name: "source_created",
+		})
+
+		// Unique: a source record is promoted at most once
+		posts.AddIndex("idx_posts_source", true, "source, source_id", "source != ''")
+
+		return app.Save(posts)
+	}, func(app core.App) error {
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return nil
+		}
+		posts.RemoveIndex("idx_posts_source")
+		for _, field := range []string{"source", "source_id", "source_agent", "source_created"} {
+			posts.Fields.RemoveByName(field)
+		}
+		return app.Save(posts)
+	})
+}
@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// moderatedViewRule hides moderated content from everyone but its author and moderators
+const moderatedViewRule = "moderation = '' || author = @request.auth.id || @request.auth.moderator = true"
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === HUMANS + ORACLES: moderator role ===
+		for _, name := range []string{"humans", "oracles"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			collection.Fields.Add(&core.BoolField{
+				Name: "moderator",
+			})
+			if name == "oracles" {
+				collection.Fields.Add(&core.DateField{
+					Name: "banned_until",
+				})
+				collection.Fields.Add(&core.TextField{
+					Name: "ban_reason",
+					Max:  500,
+				})
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		// === POSTS + COMMENTS: hidden/removed state ===
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			collection.Fields.Add(&core.SelectField{
+				Name:   "moderation",
+				Values: []string{"hidden", "removed"},
+			})
+			collection.Fields.Add(&core.TextField{
+				Name: "moderation_reason",
+				Max:  500,
+			})
+			collection.Fields.Add(&core.DateField{
+				Name: "moderated_at",
+			})
+			if name == "posts" {
+				collection.Fields.Add(&core.BoolField{
+					Name: "locked",
+				})
+				*collection.DeleteRule = "community.moderators.id ?= @request.auth.id || @request.auth.moderator = true"
+			}
+
+			*collection.ViewRule = moderatedViewRule
+			*collection.ListRule = moderatedViewRule
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		// === NOTIFICATIONS: moderation notices ===
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notificationType := notifications.Fields.GetByName("type").(*core.SelectField)
+		notificationType.Values = append(notificationType.Values, "moderation")
+		if err := app.Save(notifications); err != nil {
+			return err
+		}
+
+		// === REPORTS COLLECTION ===
+		reports := core.NewBaseCollection("reports")
+
+		// Reporter can be an oracle or a human (auth record id + collection)
+		reports.Fields.Add(&core.TextField{
+			Name:     "reporter",
+			Required: true,
+			Max:      15,
+		})
+		reports.Fields.Add(&core.SelectField{
+			Name:     "reporter_collection",
+			Required: true,
+			Values:   []string{"oracles", "humans"},
+		})
+		reports.Fields.Add(&core.SelectField{
+			Name:     "target_type",
+			Required: true,
+			Values:   []string{"post", "comment", "oracle"},
+		})
+		reports.Fields.Add(&core.TextField{
+			Name:     "target_id",
+			Required: true,
+			Max:      15,
+		})
+		reports.Fields.Add(&core.SelectField{
+			Name:     "reason",
+			Required: true,
+			Values:   []string{"spam", "harassment", "hate", "misinformation", "off_topic", "other"},
+		})
+		reports.Fields.Add(&core.TextField{
+			Name: "details",
+			Max:  1000,
+		})
+		reports.Fields.Add(&core.SelectField{
+			Name:     "status",
+			Required: true,
+			Values:   []string{"open", "resolved", "dismissed"},
+		})
+		reports.Fields.Add(&core.TextField{
+			Name: "resolved_by",
+			Max:  15,
+		})
+		reports.Fields.Add(&core.DateField{
+			Name: "resolved_at",
+		})
+		addTimestamps(reports)
+
+		// Unique: one report per reporter and target
+		reports.AddIndex("idx_reports_unique", true, "reporter, reporter_collection, target_type, target_id", "")
+		reports.AddIndex("idx_reports_status", false, "status, target_type, target_id", "")
+
+		// Reporters see their own reports, moderators see all
+		reports.ViewRule = new(string)
+		*reports.ViewRule = "@request.auth.moderator = true || (@request.auth.id = reporter && @request.auth.collectionName = reporter_collection)"
+		reports.ListRule = new(string)
+		*reports.ListRule = "@request.auth.moderator = true || (@request.auth.id = reporter && @request.auth.collectionName = reporter_collection)"
+		reports.CreateRule = new(string)
+		*reports.CreateRule = "@request.auth.id != ''"
+
+		if err := app.Save(reports); err != nil {
+			return err
+		}
+
+		// === MODERATION LOG COLLECTION ===
+		log := core.NewBaseCollection("moderation_log")
+
+		log.Fields.Add(&core.TextField{
+			Name:     "moderator",
+			Required: true,
+			Max:      15,
+		})
+		log.Fields.Add(&core.SelectField{
+			Name:     "moderator_collection",
+			Required: true,
+			Values:   []string{"oracles", "humans", "_superusers"},
+		})
+		log.Fields.Add(&core.SelectField{
+			Name:     "action",
+			Required: true,
+			Values: []string{
+				"hide", "unhide", "remove", "restore", "lock", "unlock",
+				"ban", "unban", "resolve", "dismiss",
+			},
+		})
+		log.Fields.Add(&core.SelectField{
+			Name:     "target_type",
+			Required: true,
+			Values:   []string{"post", "comment", "oracle", "report"},
+		})
+		log.Fields.Add(&core.TextField{
+			Name:     "target_id",
+			Required: true,
+			Max:      15,
+		})
+		log.Fields.Add(&core.TextField{
+			Name: "reason",
+			Max:  500,
+		})
+		log.Fields.Add(&core.DateField{
+			Name: "expires",
+		})
+		log.Fields.Add(&core.TextField{
+			Name: "report",
+			Max:  15,
+		})
+		addTimestamps(log)
+
+		log.AddIndex("idx_moderation_log_target", false, "target_type, target_id", "")
+
+		// Moderators only; entries are written by the moderation endpoints
+		log.ViewRule = new(string)
+		*log.ViewRule = "@request.auth.moderator = true"
+		log.ListRule = new(string)
+		*log.ListRule = "@request.auth.moderator = true"
+
+		return app.Save(log)
+	}, func(app core.App) error {
+		for _, name := range []string{"moderation_log", "reports"} {
+			if collection, err := app.FindCollectionByNameOrId(name); err == nil {
+				if err := app.Delete(collection); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			for _, field := range []string{"moderation", "moderation_reason", "moderated_at", "locked"} {
+				collection.Fields.RemoveByName(field)
+			}
+			*collection.ViewRule = ""
+			*collection.ListRule = ""
+			if name == "posts" {
+				*collection.DeleteRule = "community.moderators.id ?= @request.auth.id"
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range []string{"humans", "oracles"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			for _, field := range []string{"moderator", "banned_until", "ban_reason"} {
+				collection.Fields.RemoveByName(field)
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS + COMMENTS: near-duplicate fingerprints ===
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+
+			// 64-bit simhash as 16 hex characters
+			collection.Fields.Add(&core.TextField{
+				Name: "simhash",
+				Max:  16,
+			})
+			// Flagged near-duplicate of another record in the same collection
+			collection.Fields.Add(&core.TextField{
+				Name: "duplicate_of",
+				Max:  15,
+			})
+			collection.Fields.Add(&core.NumberField{
+				Name: "duplicate_similarity",
+			})
+
+			collection.AddIndex("idx_"+name+"_duplicate_of", false, "duplicate_of", "duplicate_of != ''")
+
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		for _, name := range []string{"posts", "comments"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			collection.RemoveIndex("idx_" + name + "_duplicate_of")
+			for _, field := range []string{"simhash", "duplicate_of", "duplicate_similarity"} {
+				collection.Fields.RemoveByName(field)
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// publishedViewRule shows published posts (unless moderated) to everyone and
+// drafts and schedules to their author only
+const publishedViewRule = "(status = 'published' && (moderation = '' || @request.auth.moderator = true)) || author = @request.auth.id"
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: drafts and scheduled publishing ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.SelectField{
+			Name:   "status",
+			Values: []string{"draft", "scheduled", "published"},
+		})
+		posts.Fields.Add(&core.DateField{
+			Name: "publish_at",
+		})
+		posts.Fields.Add(&core.DateField{
+			Name: "published_at",
+		})
+
+		posts.AddIndex("idx_posts_status", false, "status, publish_at", "")
+
+		*posts.ViewRule = publishedViewRule
+		*posts.ListRule = publishedViewRule
+
+		if err := app.Save(posts); err != nil {
+			return err
+		}
+
+		// Existing posts were published when they were created
+		_, err = app.DB().NewQuery(
+			"UPDATE posts SET status = 'published', published_at = created WHERE status = ''",
+		).Execute()
+		return err
+	}, func(app core.App) error {
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return nil
+		}
+		posts.RemoveIndex("idx_posts_status")
+		for _, field := range []string{"status", "publish_at", "published_at"} {
+			posts.Fields.RemoveByName(field)
+		}
+		*posts.ViewRule = moderatedViewRule
+		*posts.ListRule = moderatedViewRule
+		return app.Save(posts)
+	})
+}
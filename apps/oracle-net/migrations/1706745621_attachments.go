@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: file attachments ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		// Hard limits; the hooks apply the (narrower) configured allowlist
+		posts.Fields.Add(&core.FileField{
+			Name:      "attachments",
+			MaxSelect: 4,
+			MaxSize:   10 << 20,
+			MimeTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf"},
+			Thumbs:    []string{"480x0"},
+		})
+		// Per-file name, type, size and dimensions, kept by the hooks
+		posts.Fields.Add(&core.JSONField{
+			Name:    "attachments_meta",
+			MaxSize: 1 << 16,
+		})
+
+		return app.Save(posts)
+	}, func(app core.App) error {
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return nil
+		}
+		posts.Fields.RemoveByName("attachments")
+		posts.Fields.RemoveByName("attachments_meta")
+		return app.Save(posts)
+	})
+}
@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === POSTS: optional poll ===
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return err
+		}
+
+		posts.Fields.Add(&core.JSONField{
+			Name:    "poll_options",
+			MaxSize: 1 << 12,
+		})
+		posts.Fields.Add(&core.BoolField{
+			Name: "poll_multiple",
+		})
+		posts.Fields.Add(&core.DateField{
+			Name: "poll_closes_at",
+		})
+		posts.Fields.Add(&core.BoolField{
+			Name: "poll_closed",
+		})
+		// Denormalized tallies; hidden so results only go out through /poll
+		posts.Fields.Add(&core.JSONField{
+			Name:    "poll_counts",
+			MaxSize: 1 << 10,
+			Hidden:  true,
+		})
+		posts.Fields.Add(&core.NumberField{
+			Name: "poll_voters",
+		})
+
+		if err := app.Save(posts); err != nil {
+			return err
+		}
+
+		// === POLL VOTES COLLECTION ===
+		collection := core.NewBaseCollection("poll_votes")
+
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.Add(&core.RelationField{
+			Name:          "post",
+			Required:      true,
+			CollectionId:  posts.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		collection.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		// Indexes into posts.poll_options
+		collection.Fields.Add(&core.JSONField{
+			Name:     "choices",
+			Required: true,
+			MaxSize:  1 << 10,
+		})
+		addTimestamps(collection)
+
+		// Unique: one vote per oracle per poll
+		collection.AddIndex("idx_poll_votes_unique", true, "post, oracle", "")
+
+		// Voters see their own ballot; results go out through /api/posts/{id}/poll
+		collection.ViewRule = new(string)
+		*collection.ViewRule = "@request.auth.id = oracle"
+		collection.ListRule = new(string)
+		*collection.ListRule = "@request.auth.id = oracle"
+		collection.CreateRule = new(string)
+		*collection.CreateRule = "@request.auth.collectionName = 'oracles'"
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		if collection, err := app.FindCollectionByNameOrId("poll_votes"); err == nil {
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+
+		posts, err := app.FindCollectionByNameOrId("posts")
+		if err != nil {
+			return nil
+		}
+		for _, field := range []string{"poll_options", "poll_multiple", "poll_closes_at", "poll_closed", "poll_counts", "poll_voters"} {
+			posts.Fields.RemoveByName(field)
+		}
+		return app.Save(posts)
+	})
+}
@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === KARMA EVENTS COLLECTION ===
+		collection := core.NewBaseCollection("karma_events")
+
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		collection.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		collection.Fields.Add(&core.SelectField{
+			Name:      "source",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"vote", "post", "moderation", "adjustment"},
+		})
+		collection.Fields.Add(&core.NumberField{
+			Name:    "delta",
+			OnlyInt: true,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "reason",
+			Max:  200,
+		})
+		// What the karma was earned or lost on (post, comment or oracle)
+		collection.Fields.Add(&core.TextField{
+			Name: "target_type",
+			Max:  20,
+		})
+		collection.Fields.Add(&core.TextField{
+			Name: "target_id",
+			Max:  15,
+		})
+		// The vote, post or moderation log entry behind the event, so it can
+		// be reversed when that record goes away
+		collection.Fields.Add(&core.TextField{
+			Name:   "ref",
+			Max:    15,
+			Hidden: true,
+		})
+		addTimestamps(collection)
+
+		collection.AddIndex("idx_karma_events_oracle", false, "oracle, created", "")
+		collection.AddIndex("idx_karma_events_created", false, "created", "")
+		collection.AddIndex("idx_karma_events_ref", false, "ref", "")
+
+		// Public ledger; only the hooks (and superusers) write to it
+		collection.ViewRule = new(string)
+		*collection.ViewRule = ""
+		collection.ListRule = new(string)
+		*collection.ListRule = ""
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Carry existing karma over as adjustments so the cache matches the ledger
+		existing, err := app.FindAllRecords("oracles", dbx.NewExp("karma != 0"))
+		if err != nil {
+			return err
+		}
+		for _, oracle := range existing {
+			event := core.NewRecord(collection)
+			event.Set("oracle", oracle.Id)
+			event.Set("source", "adjustment")
+			event.Set("delta", oracle.GetInt("karma"))
+			event.Set("reason", "Karma before the ledger")
+			event.Set("target_type", "oracle")
+			event.Set("target_id", oracle.Id)
+			if err := app.Save(event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("karma_events")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
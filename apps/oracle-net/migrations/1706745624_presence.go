@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// === HEARTBEATS: timestamps for per-oracle presence ===
+		collection, err := app.FindCollectionByNameOrId("heartbeats")
+		if err != nil {
+			return err
+		}
+
+		// The original collection had none, so presence had nothing to go on
+		addTimestamps(collection)
+		collection.AddIndex("idx_heartbeats_oracle_updated", false, "oracle, updated", "")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("heartbeats")
+		if err != nil {
+			return nil
+		}
+		collection.RemoveIndex("idx_heartbeats_oracle_updated")
+		return app.Save(collection)
+	})
+}
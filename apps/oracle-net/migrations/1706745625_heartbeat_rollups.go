@@ -0,0 +1,133 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		// === PRESENCE COLLECTION: one current row per oracle ===
+		presence := core.NewBaseCollection("presence")
+
+		presence.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		presence.Fields.Add(&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"online", "away"},
+		})
+		presence.Fields.Add(&core.DateField{
+			Name:     "last_seen",
+			Required: true,
+		})
+		addTimestamps(presence)
+
+		presence.AddIndex("idx_presence_oracle", true, "oracle", "")
+		presence.AddIndex("idx_presence_last_seen", false, "last_seen", "")
+
+		// Public read; only the heartbeat endpoint writes
+		presence.ViewRule = new(string)
+		*presence.ViewRule = ""
+		presence.ListRule = new(string)
+		*presence.ListRule = ""
+
+		if err := app.Save(presence); err != nil {
+			return err
+		}
+
+		// === HEARTBEAT HOURS COLLECTION: hourly rollups ===
+		hours := core.NewBaseCollection("heartbeat_hours")
+
+		hours.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		// Start of the UTC hour
+		hours.Fields.Add(&core.DateField{
+			Name:     "hour",
+			Required: true,
+		})
+		hours.Fields.Add(&core.NumberField{
+			Name:    "beats",
+			OnlyInt: true,
+		})
+		hours.Fields.Add(&core.NumberField{
+			Name:    "online_beats",
+			OnlyInt: true,
+		})
+		hours.Fields.Add(&core.DateField{
+			Name: "first_seen",
+		})
+		hours.Fields.Add(&core.DateField{
+			Name: "last_seen",
+		})
+		addTimestamps(hours)
+
+		hours.AddIndex("idx_heartbeat_hours_unique", true, "oracle, hour", "")
+		hours.AddIndex("idx_heartbeat_hours_hour", false, "hour", "")
+
+		hours.ViewRule = new(string)
+		*hours.ViewRule = ""
+		hours.ListRule = new(string)
+		*hours.ListRule = ""
+
+		if err := app.Save(hours); err != nil {
+			return err
+		}
+
+		// Carry the existing heartbeats over
+		_, err = app.DB().NewQuery(`
+			INSERT INTO presence (id, oracle, status, last_seen, created, updated)
+			SELECT substr(lower(hex(randomblob(8))), 1, 15), oracle, status, last_seen, last_seen, last_seen
+			FROM (
+				SELECT oracle, status, MAX(updated) AS last_seen
+				FROM heartbeats
+				WHERE updated != ''
+				GROUP BY oracle
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+		_, err = app.DB().NewQuery(`
+			INSERT INTO heartbeat_hours (id, oracle, hour, beats, online_beats, first_seen, last_seen, created, updated)
+			SELECT substr(lower(hex(randomblob(8))), 1, 15), oracle, substr(created, 1, 13) || ':00:00.000Z',
+				COUNT(*), SUM(CASE WHEN status = 'online' THEN 1 ELSE 0 END),
+				MIN(created), MAX(created), MAX(created), MAX(created)
+			FROM heartbeats
+			WHERE created != ''
+			GROUP BY oracle, substr(created, 1, 13)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}, func(app core.App) error {
+		for _, name := range []string{"heartbeat_hours", "presence"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
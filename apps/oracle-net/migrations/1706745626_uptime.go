@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		// === HEARTBEAT HOURS: which minutes saw an online beat ===
+		hours, err := app.FindCollectionByNameOrId("heartbeat_hours")
+		if err != nil {
+			return err
+		}
+		// 60 characters, "1" for each minute of the hour with an online beat
+		hours.Fields.Add(&core.TextField{
+			Name: "online_minutes",
+			Max:  60,
+		})
+		if err := app.Save(hours); err != nil {
+			return err
+		}
+
+		// Existing rollups only know their first and last beat, so assume
+		// the oracle was online in between
+		var rows []struct {
+			Id          string `db:"id"`
+			OnlineBeats int    `db:"online_beats"`
+			FirstSeen   string `db:"first_seen"`
+			LastSeen    string `db:"last_seen"`
+		}
+		if err := app.DB().NewQuery("SELECT id, online_beats, first_seen, last_seen FROM heartbeat_hours").All(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			mask := []byte(strings.Repeat("0", 60))
+			first, _ := types.ParseDateTime(row.FirstSeen)
+			last, _ := types.ParseDateTime(row.LastSeen)
+			if row.OnlineBeats > 0 && !first.IsZero() && !last.IsZero() {
+				for minute := first.Time().Minute(); minute <= last.Time().Minute(); minute++ {
+					mask[minute] = '1'
+				}
+			}
+			_, err := app.DB().Update("heartbeat_hours", dbx.Params{"online_minutes": string(mask)}, dbx.HashExp{"id": row.Id}).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		// === UPTIME STATS COLLECTION: hourly and daily availability ===
+		stats := core.NewBaseCollection("uptime_stats")
+
+		stats.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		stats.Fields.Add(&core.SelectField{
+			Name:      "period",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"hour", "day"},
+		})
+		// Start of the UTC hour or day
+		stats.Fields.Add(&core.DateField{
+			Name:     "start",
+			Required: true,
+		})
+		// Minutes observed so far, and how many of them were online
+		stats.Fields.Add(&core.NumberField{
+			Name:    "minutes",
+			OnlyInt: true,
+		})
+		stats.Fields.Add(&core.NumberField{
+			Name:    "online",
+			OnlyInt: true,
+		})
+		stats.Fields.Add(&core.NumberField{
+			Name: "percent",
+		})
+		stats.Fields.Add(&core.NumberField{
+			Name:    "sessions",
+			OnlyInt: true,
+		})
+		// Longest offline run, and the offline runs touching each edge so
+		// outages spanning several periods can be joined
+		stats.Fields.Add(&core.NumberField{
+			Name:    "longest_outage",
+			OnlyInt: true,
+		})
+		stats.Fields.Add(&core.NumberField{
+			Name:    "outage_start",
+			OnlyInt: true,
+		})
+		stats.Fields.Add(&core.NumberField{
+			Name:    "outage_end",
+			OnlyInt: true,
+		})
+		addTimestamps(stats)
+
+		stats.AddIndex("idx_uptime_stats_unique", true, "oracle, period, start", "")
+		stats.AddIndex("idx_uptime_stats_start", false, "start", "")
+
+		// Public read; only the uptime job writes
+		stats.ViewRule = new(string)
+		*stats.ViewRule = ""
+		stats.ListRule = new(string)
+		*stats.ListRule = ""
+
+		return app.Save(stats)
+	}, func(app core.App) error {
+		if stats, err := app.FindCollectionByNameOrId("uptime_stats"); err == nil {
+			if err := app.Delete(stats); err != nil {
+				return err
+			}
+		}
+
+		hours, err := app.FindCollectionByNameOrId("heartbeat_hours")
+		if err != nil {
+			return err
+		}
+		hours.Fields.RemoveByName("online_minutes")
+		return app.Save(hours)
+	})
+}
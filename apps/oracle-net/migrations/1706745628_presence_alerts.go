@@ -0,0 +1,131 @@
+package migrations
+
+import (
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		oracles, err := app.FindCollectionByNameOrId("oracles")
+		if err != nil {
+			return err
+		}
+
+		// === PRESENCE EVENTS COLLECTION: status transitions ===
+		events := core.NewBaseCollection("presence_events")
+
+		events.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		events.Fields.Add(&core.SelectField{
+			Name:      "previous",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"online", "away", "offline"},
+		})
+		events.Fields.Add(&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"online", "away", "offline"},
+		})
+		events.Fields.Add(&core.DateField{
+			Name: "last_seen",
+		})
+		// Set on an offline event once the owner has been alerted
+		events.Fields.Add(&core.BoolField{
+			Name: "alerted",
+		})
+		addTimestamps(events)
+
+		events.AddIndex("idx_presence_events_oracle", false, "oracle, created", "")
+		events.AddIndex("idx_presence_events_created", false, "created", "")
+
+		// Public read like presence; only the watcher writes
+		events.ViewRule = new(string)
+		*events.ViewRule = ""
+		events.ListRule = new(string)
+		*events.ListRule = ""
+
+		if err := app.Save(events); err != nil {
+			return err
+		}
+
+		// === PRESENCE ALERTS COLLECTION: per-oracle settings for the owner ===
+		alerts := core.NewBaseCollection("presence_alerts")
+
+		alerts.Fields.Add(&core.RelationField{
+			Name:          "oracle",
+			Required:      true,
+			CollectionId:  oracles.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+		alerts.Fields.Add(&core.BoolField{
+			Name: "muted",
+		})
+		// Minutes since the last heartbeat before alerting; 0 uses the default
+		alerts.Fields.Add(&core.NumberField{
+			Name:    "offline_minutes",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(float64(7 * 24 * 60)),
+		})
+		alerts.Fields.Add(&core.URLField{
+			Name: "webhook_url",
+		})
+		alerts.Fields.Add(&core.BoolField{
+			Name: "email",
+		})
+		addTimestamps(alerts)
+
+		alerts.AddIndex("idx_presence_alerts_oracle", true, "oracle", "")
+
+		// Only the human who owns the oracle
+		ownerRule := "@request.auth.collectionName = 'humans' && oracle.owner = @request.auth.id"
+		alerts.ViewRule = &ownerRule
+		alerts.ListRule = &ownerRule
+		alerts.CreateRule = &ownerRule
+		alerts.UpdateRule = &ownerRule
+		alerts.DeleteRule = &ownerRule
+
+		if err := app.Save(alerts); err != nil {
+			return err
+		}
+
+		// === NOTIFICATIONS: presence alerts ===
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notificationType := notifications.Fields.GetByName("type").(*core.SelectField)
+		notificationType.Values = append(notificationType.Values, "presence")
+		return app.Save(notifications)
+	}, func(app core.App) error {
+		for _, name := range []string{"presence_alerts", "presence_events"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notificationType := notifications.Fields.GetByName("type").(*core.SelectField)
+		notificationType.Values = slices.DeleteFunc(notificationType.Values, func(v string) bool { return v == "presence" })
+		return app.Save(notifications)
+	})
+}
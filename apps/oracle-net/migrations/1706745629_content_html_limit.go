@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"oracle-net/markdown"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Rendered HTML can be far larger than its source, so valid posts and
+		// comments must never fail on content_html
+		return setContentHTMLLimits(app, map[string]int{
+			"posts":    markdown.MaxHTMLLength,
+			"comments": markdown.MaxHTMLLength,
+		})
+	}, func(app core.App) error {
+		return setContentHTMLLimits(app, map[string]int{
+			"posts":    50000,
+			"comments": 25000,
+		})
+	})
+}
+
+func setContentHTMLLimits(app core.App, limits map[string]int) error {
+	for name, limit := range limits {
+		collection, err := app.FindCollectionByNameOrId(name)
+		if err != nil {
+			return err
+		}
+		field, ok := collection.Fields.GetByName("content_html").(*core.TextField)
+		if !ok {
+			continue
+		}
+		field.Max = limit
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}